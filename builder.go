@@ -0,0 +1,150 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import "time"
+
+// MockBuilder builds a [MockCommand] registered with a [MockExecutor]
+// through chained calls, see [MockExecutor.Expect]. Every method sets
+// the corresponding field of the command and returns the builder.
+type MockBuilder struct {
+	cmd *MockCommand
+}
+
+// Expect registers a command with the provided name and arguments,
+// returning a builder to set how it should behave, e.g.:
+//
+//	mock.Expect("git", "status").WithDir("/repo").ReturnsStdout("clean\n").ExitCode(0).Times(2)
+//
+// This is equivalent to calling [MockExecutor.AddCommand] with a
+// MockCommand, which can be retrieved through [MockBuilder.Command].
+func (e *MockExecutor) Expect(name string, args ...string) *MockBuilder {
+	cmd := &MockCommand{Name: name, Args: args}
+	e.AddCommand(cmd)
+	return &MockBuilder{cmd: cmd}
+}
+
+// Command returns the command being built.
+func (b *MockBuilder) Command() *MockCommand {
+	return b.cmd
+}
+
+// WithDir sets [MockCommand.ExpectedDir].
+func (b *MockBuilder) WithDir(dir string) *MockBuilder {
+	b.cmd.ExpectedDir = dir
+	return b
+}
+
+// WithDirPattern sets [MockCommand.ExpectedDirPattern].
+func (b *MockBuilder) WithDirPattern(pattern string) *MockBuilder {
+	b.cmd.ExpectedDirPattern = pattern
+	return b
+}
+
+// WithEnv adds env to [MockCommand.ExpectedEnv].
+func (b *MockBuilder) WithEnv(env ...string) *MockBuilder {
+	b.cmd.ExpectedEnv = append(b.cmd.ExpectedEnv, env...)
+	return b
+}
+
+// WithStdin sets [MockCommand.Stdin].
+func (b *MockBuilder) WithStdin(stdin string) *MockBuilder {
+	b.cmd.Stdin = []byte(stdin)
+	return b
+}
+
+// WithStdinJSON sets [MockCommand.Stdin] and [MockCommand.StdinJSON],
+// comparing stdin to it as parsed JSON.
+func (b *MockBuilder) WithStdinJSON(stdin string) *MockBuilder {
+	b.cmd.Stdin = []byte(stdin)
+	b.cmd.StdinJSON = true
+	return b
+}
+
+// WithStdinContaining adds fragments to [MockCommand.StdinContains].
+func (b *MockBuilder) WithStdinContaining(fragments ...string) *MockBuilder {
+	for _, fragment := range fragments {
+		b.cmd.StdinContains = append(b.cmd.StdinContains, []byte(fragment))
+	}
+	return b
+}
+
+// ReturnsStdout sets [MockCommand.Stdout].
+func (b *MockBuilder) ReturnsStdout(stdout string) *MockBuilder {
+	b.cmd.Stdout = []byte(stdout)
+	return b
+}
+
+// ReturnsStderr sets [MockCommand.Stderr].
+func (b *MockBuilder) ReturnsStderr(stderr string) *MockBuilder {
+	b.cmd.Stderr = []byte(stderr)
+	return b
+}
+
+// ReturnsCombined sets [MockCommand.Combined].
+func (b *MockBuilder) ReturnsCombined(combined string) *MockBuilder {
+	b.cmd.Combined = []byte(combined)
+	return b
+}
+
+// FailAfter sets [MockCommand.FailAfter].
+func (b *MockBuilder) FailAfter(n int) *MockBuilder {
+	b.cmd.FailAfter = n
+	return b
+}
+
+// ReturnsError sets [MockCommand.Err].
+func (b *MockBuilder) ReturnsError(err error) *MockBuilder {
+	b.cmd.Err = err
+	return b
+}
+
+// ExitCode sets [MockCommand.ExitStatus].
+func (b *MockBuilder) ExitCode(code int) *MockBuilder {
+	b.cmd.ExitStatus = code
+	return b
+}
+
+// Delay sets [MockCommand.Delay].
+func (b *MockBuilder) Delay(delay time.Duration) *MockBuilder {
+	b.cmd.Delay = delay
+	return b
+}
+
+// Times calls [MockCommand.Times].
+func (b *MockBuilder) Times(n int) *MockBuilder {
+	b.cmd.Times(n)
+	return b
+}
+
+// AtLeast calls [MockCommand.AtLeast].
+func (b *MockBuilder) AtLeast(n int) *MockBuilder {
+	b.cmd.AtLeast(n)
+	return b
+}
+
+// AtMost calls [MockCommand.AtMost].
+func (b *MockBuilder) AtMost(n int) *MockBuilder {
+	b.cmd.AtMost(n)
+	return b
+}
+
+// Uses calls [MockCommand.Uses].
+func (b *MockBuilder) Uses(n int) *MockBuilder {
+	b.cmd.Uses(n)
+	return b
+}
@@ -0,0 +1,110 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"slices"
+	"time"
+)
+
+// Invocation is a single execution of a mocked command, see
+// [MockExecutor.Calls].
+type Invocation struct {
+	// Name is the name (or path) the command was called with.
+	Name string
+
+	// Args are the arguments the command was called with.
+	Args []string
+
+	// Env is the environment of the command, see [Cmd.Environ].
+	Env []string
+
+	// Dir is the working directory of the command set through
+	// [Cmd.SetDir], or empty if it was never called.
+	Dir string
+
+	// Stdin is the input provided to the command, or nil if none was
+	// provided.
+	Stdin []byte
+
+	// TTY denotes if the command is attached to a terminal, see
+	// [Cmd.UsePTY]. This allows a [MockCommand.Handler] to behave like
+	// commands that check isatty, e.g., to only output colors then.
+	TTY bool
+
+	// Time is when the command was started.
+	Time time.Time
+}
+
+// String returns the command line of the invocation, quoting arguments
+// where needed so that it can be split back the same way as
+// [CommandString] does.
+func (inv Invocation) String() string {
+	return joinCommandLine(append([]string{inv.Name}, inv.Args...))
+}
+
+// Calls returns every execution of the commands registered with the
+// executor, in the order they were started.
+func (e *MockExecutor) Calls() []Invocation {
+	e.callsMu.Lock()
+	defer e.callsMu.Unlock()
+
+	calls := slices.Clone(e.calls)
+	slices.SortStableFunc(calls, func(a, b Invocation) int {
+		return a.Time.Compare(b.Time)
+	})
+	return calls
+}
+
+// OnMatch registers fn to be called whenever a command registered with
+// the executor is executed, with its arguments, environment, working
+// directory and stdin. This is useful for logging every command while
+// debugging why an expectation isn't met, e.g.:
+//
+//	mock.OnMatch(func(inv cmdexec.Invocation) { t.Logf("%s %v", inv.Name, inv.Args) })
+//
+// fn may be called concurrently if commands run concurrently. It
+// returns the executor to allow chaining.
+func (e *MockExecutor) OnMatch(fn func(Invocation)) *MockExecutor {
+	e.callsMu.Lock()
+	defer e.callsMu.Unlock()
+
+	e.onMatch = append(e.onMatch, fn)
+	return e
+}
+
+// record adds inv to the calls of the executor and calls the functions
+// registered through OnMatch.
+func (e *MockExecutor) record(inv Invocation) {
+	e.callsMu.Lock()
+	e.calls = append(e.calls, inv)
+	onMatch := e.onMatch
+	e.callsMu.Unlock()
+
+	for _, fn := range onMatch {
+		fn(inv)
+	}
+}
+
+// attempt records that the command called with argv was started, even
+// if it fails to, see AssertNoCommands.
+func (e *MockExecutor) attempt(argv []string) {
+	e.callsMu.Lock()
+	defer e.callsMu.Unlock()
+
+	e.attempts = append(e.attempts, argv)
+}
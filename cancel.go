@@ -0,0 +1,73 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultCancelSignal contains the signal set through
+// [SetDefaultCancelSignal].
+var defaultCancelSignal struct {
+	mu        sync.Mutex
+	sig       os.Signal
+	killAfter time.Duration
+}
+
+// SetDefaultCancelSignal sets the signal that is sent to all commands
+// created afterwards once their context is done, killing them if they
+// haven't exited killAfter later. See [Cmd.SetCancelSignal] for more
+// information. By default, like [exec.CommandContext], commands are
+// killed. A nil signal restores this default.
+//
+// Usage:
+//
+//	func main() {
+//	    cmdexec.SetDefaultCancelSignal(syscall.SIGTERM, 10*time.Second)
+//	}
+func SetDefaultCancelSignal(sig os.Signal, killAfter time.Duration) {
+	defaultCancelSignal.mu.Lock()
+	defer defaultCancelSignal.mu.Unlock()
+
+	defaultCancelSignal.sig = sig
+	defaultCancelSignal.killAfter = killAfter
+}
+
+// applyDefaultCancelSignal applies the signal set through
+// [SetDefaultCancelSignal], if any, to cmd.
+func applyDefaultCancelSignal(cmd Cmd) {
+	defaultCancelSignal.mu.Lock()
+	sig, killAfter := defaultCancelSignal.sig, defaultCancelSignal.killAfter
+	defaultCancelSignal.mu.Unlock()
+
+	if sig != nil {
+		cmd.SetCancelSignal(sig, killAfter)
+	}
+}
+
+// signalOrKill sends sig to cmd, killing it instead if the signal can't
+// be sent (e.g., because it isn't supported on this platform).
+func signalOrKill(cmd Cmd, sig os.Signal) error {
+	err := cmd.Signal(sig)
+	if err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return cmd.Kill()
+	}
+	return err
+}
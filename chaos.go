@@ -0,0 +1,141 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// ChaosFailure is a kind of failure injected into commands, see
+// [Chaos].
+type ChaosFailure int
+
+// Contains the kinds of failures that can be injected into commands.
+const (
+	// ChaosExit makes the command exit with [Chaos.ExitStatus] without
+	// running it.
+	ChaosExit ChaosFailure = iota
+
+	// ChaosTimeout makes the command fail without running it, as if it
+	// had timed out after [Chaos.Timeout] (see [Cmd.SetTimeout]).
+	ChaosTimeout
+
+	// ChaosTruncate runs the command, but only returns the first half of
+	// its output from [Cmd.Output] and [Cmd.CombinedOutput], as if it
+	// was cut short, along with an error wrapping [ErrOutputTruncated].
+	ChaosTruncate
+)
+
+// Chaos configures the failures injected into commands by
+// [Executor.WithChaos].
+type Chaos struct {
+	// Probability is the probability, between 0 and 1, of a command
+	// failing.
+	Probability float64
+
+	// Seed seeds the random number generator deciding which commands
+	// fail and how, so that a failing run can be reproduced by creating
+	// the same commands in the same order.
+	Seed uint64
+
+	// Failures are the kinds of failures that are injected, picked at
+	// random. If empty, all kinds are used.
+	Failures []ChaosFailure
+
+	// ExitStatus is the exit code of commands failing with [ChaosExit].
+	// If not set, 1 is used.
+	ExitStatus int
+
+	// Timeout is the timeout reported by commands failing with
+	// [ChaosTimeout]. If not set, 30 seconds are used.
+	Timeout time.Duration
+}
+
+// WithChaos returns an [Executor] that creates commands using e, but
+// makes them fail at random as configured by chaos. This allows
+// soak-testing retry and error handling logic, e.g.:
+//
+//	e := cmdexec.New().WithChaos(cmdexec.Chaos{Probability: 0.2, Seed: 42})
+func (e *Executor) WithChaos(chaos Chaos) *Executor {
+	if len(chaos.Failures) == 0 {
+		chaos.Failures = []ChaosFailure{ChaosExit, ChaosTimeout, ChaosTruncate}
+	}
+	if chaos.ExitStatus == 0 {
+		chaos.ExitStatus = 1
+	}
+	if chaos.Timeout == 0 {
+		chaos.Timeout = 30 * time.Second
+	}
+
+	var mu sync.Mutex
+	rng := rand.New(rand.NewPCG(chaos.Seed, chaos.Seed))
+	return &Executor{fn: func(ctx context.Context, name string, arg ...string) Cmd {
+		mu.Lock()
+		fail := rng.Float64() < chaos.Probability
+		failure := chaos.Failures[rng.IntN(len(chaos.Failures))]
+		mu.Unlock()
+
+		if !fail {
+			return e.CommandContext(ctx, name, arg...)
+		}
+
+		switch failure {
+		case ChaosTruncate:
+			return &truncatingCmd{Cmd: e.CommandContext(ctx, name, arg...)}
+		case ChaosTimeout:
+			return &MockCommand{Name: name, Args: arg, Err: newTimeoutError(chaos.Timeout), ctx: ctx}
+		default:
+			return &MockCommand{
+				Name:       name,
+				Args:       arg,
+				Stderr:     []byte("cmdexec: chaos: injected failure\n"),
+				ExitStatus: chaos.ExitStatus,
+				ctx:        ctx,
+			}
+		}
+	}}
+}
+
+// truncatingCmd is a [Cmd] whose output is cut in half, see
+// [ChaosTruncate].
+type truncatingCmd struct {
+	Cmd
+}
+
+// Output implements the [Cmd] interface, see [Cmd.Output] for more
+// information. Only the first half of the output is returned.
+func (c *truncatingCmd) Output() ([]byte, error) {
+	out, err := c.Cmd.Output()
+	return out[:len(out)/2], withTruncated(err, true)
+}
+
+// CombinedOutput implements the [Cmd] interface, see
+// [Cmd.CombinedOutput] for more information. Only the first half of the
+// output is returned.
+func (c *truncatingCmd) CombinedOutput() ([]byte, error) {
+	out, err := c.Cmd.CombinedOutput()
+	return out[:len(out)/2], withTruncated(err, true)
+}
+
+// Clone implements the [Cmd] interface, see [Cmd.Clone] for more
+// information. The clone is truncated as well.
+func (c *truncatingCmd) Clone() Cmd {
+	return &truncatingCmd{Cmd: c.Cmd.Clone()}
+}
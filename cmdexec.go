@@ -26,6 +26,9 @@ package cmdexec
 import (
 	"context"
 	"io"
+	"os"
+	"syscall"
+	"time"
 
 	"github.com/jaredallard/cmdexec/internal/mockt"
 )
@@ -39,45 +42,274 @@ type Cmd interface {
 	CombinedOutput() ([]byte, error)
 	// Run matches [exec.Cmd.Run].
 	Run() error
+	// Start matches [exec.Cmd.Start].
+	Start() error
+	// Wait matches [exec.Cmd.Wait].
+	Wait() error
+	// StdoutPipe matches [exec.Cmd.StdoutPipe].
+	StdoutPipe() (io.ReadCloser, error)
+	// StderrPipe matches [exec.Cmd.StderrPipe].
+	StderrPipe() (io.ReadCloser, error)
+	// StdinPipe matches [exec.Cmd.StdinPipe].
+	StdinPipe() (io.WriteCloser, error)
+	// ExitCode returns the exit code of the exited process, or -1 if
+	// the process hasn't exited yet or was terminated by a signal. See
+	// [os.ProcessState.ExitCode].
+	ExitCode() int
+	// PID returns the process ID of the started process, or -1 if the
+	// process hasn't been started. See [os.Process.Pid].
+	PID() int
+	// ProcessState returns information about the exited process, or nil
+	// if the process hasn't exited yet. See [exec.Cmd.ProcessState].
+	ProcessState() *ProcessState
+	// Usage returns the resource usage of the command once it has
+	// exited, such as the CPU time it used. Usage returns nil if the
+	// command hasn't exited.
+	Usage() *Usage
+
+	// Signal sends a signal to the started process. See
+	// [os.Process.Signal].
+	Signal(os.Signal) error
+	// Kill causes the started process to exit immediately. See
+	// [os.Process.Kill].
+	Kill() error
 	// String returns the command line string that will be executed.
 	String() string
+	// Environ matches [exec.Cmd.Environ].
+	Environ() []string
+	// Path returns the path of the command to run, see [exec.Cmd.Path].
+	Path() string
+	// Argv returns the command line arguments, including the command as
+	// the first element, see [exec.Cmd.Args].
+	Argv() []string
 
 	// Below are non-standard functions (no present in the [exec.Cmd])
 	// that are provided for convenience.
 
+	// Done returns a channel that is closed once a started command has
+	// exited, allowing to select on its completion. Wait returns the
+	// result of the command once Done is closed. Like Wait, Done closes
+	// any pipes of the command once it has exited, so reads from them
+	// must be done by then. Done returns nil if the command hasn't been
+	// started.
+	Done() <-chan struct{}
+
+	// RunReport runs the command like Run, returning a [RunResult]
+	// describing the execution, including its captured stdout and
+	// stderr. Output is still written to any configured stdout and
+	// stderr. The result is returned even if the command fails.
+	RunReport() (*RunResult, error)
+
+	// Clone returns a fresh, runnable copy of the command with the same
+	// name, arguments, environment, directory, stdio, and other settings
+	// configured through this interface. Unlike [exec.Cmd], which can
+	// only be run once, this allows reusing a configured command. A
+	// function set through SetCancel is not copied, as it usually refers
+	// to the original command.
+	Clone() Cmd
+
 	// SetEnviron sets the environment variables of the command. Matches
 	// the behavior of setting [exec.Cmd.Environ] directly.
 	SetEnviron([]string)
 
+	// AppendEnv merges the provided "key=value" pairs into the
+	// environment of the command, replacing any existing values of the
+	// same keys. Later pairs take precedence over earlier ones. If the
+	// environment was never set, it starts from [os.Environ].
+	AppendEnv(kv ...string)
+
+	// SetEnv sets the environment variable key to value, see AppendEnv.
+	SetEnv(key, value string)
+
+	// ScrubEnv removes all variables whose keys match any of the
+	// provided patterns (e.g., "AWS_*" or "GITHUB_TOKEN") from the
+	// environment of the command, so that it doesn't see secrets it
+	// doesn't need. See [path.Match] for the pattern syntax. If the
+	// environment was never set, it starts from [os.Environ]. Variables
+	// set afterwards are not removed.
+	ScrubEnv(patterns ...string)
+
 	// SetDir sets the working directory of the command.
 	SetDir(string)
 
+	// SetSysProcAttr sets the platform-specific attributes of the
+	// command (e.g., Setsid or CreationFlags). Matches the behavior of
+	// setting [exec.Cmd.SysProcAttr] directly.
+	SetSysProcAttr(*syscall.SysProcAttr)
+
+	// SetCredential sets the user and group ID the command is run as,
+	// allowing a privileged process to drop privileges for its children.
+	// This is only supported on Unix, on other platforms Start returns
+	// an error.
+	SetCredential(uid, gid uint32)
+
+	// SetNice sets the niceness of the command, from -20 (highest
+	// priority) to 19 (lowest priority), like the nice command. On
+	// Windows, the closest priority class is used instead. The priority
+	// is applied right after the process has started, so children
+	// spawned before then keep the default priority. If it can't be
+	// applied, the process is killed and Start returns an error.
+	SetNice(level int)
+
+	// SetRlimits sets resource limits for the command, such as its
+	// maximum address space or CPU time. The limits are set before the
+	// command is executed, by running it through /bin/sh (see the ulimit
+	// builtin), so they apply from its very start. If they can't be set,
+	// e.g., because raising a hard limit requires privileges, the command
+	// isn't run and exits with status 126 instead. This is only supported
+	// on Unix, on other platforms Start returns an error without starting
+	// the process.
+	SetRlimits(limits ...Rlimit)
+
+	// SetNewProcessGroup sets if the command should be started in a new
+	// process group so that it, and any children it spawns, can be
+	// terminated together using KillGroup. On Windows, the process is
+	// started with CREATE_NEW_PROCESS_GROUP and assigned to a new Job
+	// Object.
+	SetNewProcessGroup(bool)
+
+	// KillGroup kills the process group of a command started in a new
+	// process group (see SetNewProcessGroup). On Windows, the Job Object
+	// of the process is terminated instead.
+	KillGroup() error
+
+	// KillTree kills the command and all of its descendants, e.g., the
+	// processes spawned by a shell script. Descendants are found by
+	// walking the process tree, which is best effort: processes that
+	// were re-parented (e.g., daemons) are only killed if the command
+	// was started in a new process group (see SetNewProcessGroup).
+	KillTree() error
+
+	// SetExtraFiles sets additional open files to be inherited by the
+	// command, starting at file descriptor 3. Matches the behavior of
+	// setting [exec.Cmd.ExtraFiles] directly.
+	SetExtraFiles([]*os.File)
+
+	// SetCancel sets the function called when the context of the
+	// command is done, e.g., to send SIGTERM instead of killing the
+	// process. Matches the behavior of setting [exec.Cmd.Cancel]
+	// directly.
+	SetCancel(func() error)
+
+	// SetCancelSignal sets the signal sent to the command when its
+	// context is done (e.g., SIGTERM or SIGINT) instead of killing it,
+	// giving it a chance to clean up. If it hasn't exited killAfter
+	// later, it is killed. If the signal can't be sent, the process is
+	// killed right away. This is a shorthand for SetCancel and
+	// SetWaitDelay, see [SetDefaultCancelSignal] to apply it to all
+	// commands.
+	SetCancelSignal(sig os.Signal, killAfter time.Duration)
+
+	// SetTimeout sets the maximum amount of time the command may run
+	// for once started. If exceeded, the command is canceled as if its
+	// context was done (see SetCancel) and Wait returns an error
+	// wrapping [context.DeadlineExceeded]. A timeout of zero disables
+	// it.
+	SetTimeout(time.Duration)
+
+	// SetWaitDelay sets how long to wait for the command to exit, and
+	// its I/O pipes to close, after the context is done before the
+	// process is forcefully killed. Matches the behavior of setting
+	// [exec.Cmd.WaitDelay] directly.
+	SetWaitDelay(time.Duration)
+
 	// SetStdout, SetStderr, and SetStdin set the stdout, stderr, and
 	// stdin of the command respectively.
 	SetStdout(io.Writer)
 	SetStderr(io.Writer)
 	SetStdin(io.Reader)
 
+	// SetStdinString and SetStdinBytes set the stdin of the command to
+	// the provided data. They are shorthands for calling SetStdin with a
+	// [strings.Reader] or [bytes.Reader].
+	SetStdinString(string)
+	SetStdinBytes([]byte)
+
+	// OnStdoutLine and OnStderrLine set a function that is called with
+	// every line the command writes to stdout or stderr respectively, as
+	// the output arrives. Lines are passed without their trailing
+	// newline. Output is still written to any configured stdout and
+	// stderr. The functions are not called for output read through
+	// StdoutPipe or StderrPipe.
+	OnStdoutLine(func(string))
+	OnStderrLine(func(string))
+
+	// SetStdoutFile and SetStderrFile redirect the stdout or stderr of
+	// the command to the file at path, which is created if it doesn't
+	// exist. The file is opened when the command is started, and closed
+	// once it has exited. If both refer to the same file, it is shared.
+	SetStdoutFile(path string, mode WriteMode)
+	SetStderrFile(path string, mode WriteMode)
+
+	// SetMaxOutputBytes limits how much output Output and
+	// CombinedOutput buffer in memory. Once the limit is exceeded, the
+	// rest of the output is discarded and the truncated output is
+	// returned along with an error wrapping [ErrOutputTruncated]. A limit
+	// of zero disables it.
+	SetMaxOutputBytes(n int64)
+
+	// TeeStdout and TeeStderr set a writer that everything the command
+	// writes to stdout or stderr respectively is also written to. Unlike
+	// SetStdout and SetStderr, this can be combined with Output and
+	// CombinedOutput, e.g., to stream output to the terminal while also
+	// capturing it. Like OnStdoutLine, this doesn't apply to output read
+	// through StdoutPipe or StderrPipe.
+	TeeStdout(io.Writer)
+	TeeStderr(io.Writer)
+
 	// UseOSStreams sets Stdout, Stderr, and Stdin to the OS streams
 	// (os.Stdout, os.Stderr, and os.Stdin respectively). If stdin is
 	// false then Stdin is not set.
 	UseOSStreams(stdin bool)
+
+	// UsePTY attaches the command to a new pseudo-terminal (PTY) of the
+	// given size when started, for commands that behave differently when
+	// not ran in a terminal (e.g., colors or prompts). Stdin, if set, is
+	// copied to the terminal and all output from the terminal is copied
+	// to Stdout (or Stderr, if Stdout isn't set). PTYs are not supported
+	// on Windows.
+	UsePTY(rows, cols uint16)
+
+	// PTY returns the controlling side of the PTY the command is
+	// attached to, which can be used to interact with the command. This
+	// is nil until a command using UsePTY has been started.
+	PTY() io.ReadWriter
+
+	// ResizePTY changes the size of the PTY the command is attached to,
+	// e.g., when the terminal of the user was resized. An error is
+	// returned if the command isn't attached to a PTY.
+	ResizePTY(rows, cols uint16) error
 }
 
 // Command returns a new Cmd that will call the given command with the
 // given arguments. See [exec.Command] for more information.
 func Command(name string, arg ...string) Cmd {
-	return CommandContext(context.Background(), name, arg...)
+	return Default().Command(name, arg...)
 }
 
 // CommandContext returns a new Cmd that will call the given command with
 // the given arguments and the given context. See [exec.CommandContext]
-// for more information.
+// for more information. If ctx carries a mock executor (see
+// [WithMockExecutor]), it is used to create the command.
 func CommandContext(ctx context.Context, name string, arg ...string) Cmd {
-	executorRLock.Lock()
-	defer executorRLock.Unlock()
+	return Default().CommandContext(ctx, name, arg...)
+}
 
-	return executor(ctx, name, arg...)
+// CommandString returns a new Cmd for the provided command line and
+// the given context. The command line is split into a name and
+// arguments using POSIX shell quoting rules, for example:
+//
+//	cmdexec.CommandString(ctx, "git commit -m 'a message'")
+//
+// is equivalent to:
+//
+//	cmdexec.CommandContext(ctx, "git", "commit", "-m", "a message")
+//
+// No shell is invoked and no expansions (variables, globs, etc.) are
+// performed. An error is returned if the command line is empty or has
+// unterminated quotes.
+func CommandString(ctx context.Context, cmdline string) (Cmd, error) {
+	return Default().CommandString(ctx, cmdline)
 }
 
 // UseMockExecutor replaces the executor used by cmdexec with a mock
@@ -86,6 +318,9 @@ func CommandContext(ctx context.Context, name string, arg ...string) Cmd {
 // to the test to ensure that the original executor is restored after
 // the test has finished.
 //
+// Once the test has finished, the expectations set on the registered
+// commands (e.g., [MockCommand.Times]) are verified.
+//
 // Note: This function can only ever be called once per test. If called
 // again in the same test, it will cause the test to fail.
 //
@@ -104,29 +339,32 @@ func CommandContext(ctx context.Context, name string, arg ...string) Cmd {
 //	    // Your test code here.
 //	}
 func UseMockExecutor(t mockt.T, mock *MockExecutor) {
-	// Prevent new mock executors from being used until this test has finished.
-	if !executorWLock.TryLock() {
-		t.Fatal("UseMockExecutor can only be called once per test")
-		return
-	}
-
-	// Lock the reader to prevent new commands from being created while we
-	// swap out the executor.
-	executorRLock.Lock()
-	originalExecutor := executor
-	executor = mock.executor
-	executorRLock.Unlock()
+	useExecutor(t, "UseMockExecutor", mock.executor, func() {
+		mock.verify(t)
+	})
+}
 
+// WithMockExecutor returns a copy of ctx that makes commands created
+// with it (see [CommandContext]) use the provided mock executor. Unlike
+// [UseMockExecutor], the executor used by the rest of the package is
+// left untouched, so parallel tests can each use their own mock
+// executor:
+//
+//	func TestSomething(t *testing.T) {
+//	    t.Parallel()
+//
+//	    ctx := cmdexec.WithMockExecutor(context.Background(), t, cmdexec.NewMockExecutor(
+//	        &cmdexec.MockCommand{Name: "echo", Args: []string{"hello"}, Stdout: []byte("hello\n")},
+//	    ))
+//
+//	    // Pass ctx to the code under test.
+//	}
+//
+// Like [UseMockExecutor], the expectations set on the registered
+// commands are verified once the test has finished.
+func WithMockExecutor(ctx context.Context, t mockt.T, mock *MockExecutor) context.Context {
 	t.Cleanup(func() {
-		// Lock the reader again to prevent new commands from being created
-		// while we restore the original executor.
-		executorRLock.Lock()
-
-		// Unlock the reader and writer once we're done.
-		defer executorRLock.Unlock()
-		defer executorWLock.Unlock()
-
-		// Restore the original executor.
-		executor = originalExecutor
+		mock.verify(t)
 	})
+	return context.WithValue(ctx, executorKey{}, executorFn(mock.executor))
 }
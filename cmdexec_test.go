@@ -1,6 +1,11 @@
 package cmdexec_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
 	"testing"
 
 	"github.com/jaredallard/cmdexec"
@@ -18,3 +23,266 @@ func TestTestIsFailedIfMultipleExecutors(t *testing.T) {
 
 	assert.Equal(t, subT.Failed(), true, "expected sub-test to fail")
 }
+
+// TestCommandString ensures that command lines are split using POSIX
+// shell quoting rules and can be mocked by their full string.
+func TestCommandString(t *testing.T) {
+	mock := cmdexec.NewMockExecutor()
+	assert.NilError(t, mock.AddCommandString(`git commit -m 'a message'`, &cmdexec.MockCommand{
+		Stdout: []byte("ok"),
+	}))
+	assert.NilError(t, mock.AddCommandString(`echo "a \"b\" \$c" d\ e '\n'`, &cmdexec.MockCommand{}))
+	cmdexec.UseMockExecutor(t, mock)
+
+	cmd, err := cmdexec.CommandString(context.Background(), "git  commit -m 'a message'")
+	assert.NilError(t, err)
+	out, err := cmd.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "ok")
+
+	cmd, err = cmdexec.CommandString(context.Background(), `echo "a \"b\" \$c" d\ e '\n'`)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, cmd.Argv(), []string{"echo", `a "b" $c`, "d e", `\n`})
+
+	_, err = cmdexec.CommandString(context.Background(), `echo 'unterminated`)
+	assert.ErrorContains(t, err, "unterminated single quote")
+
+	_, err = cmdexec.CommandString(context.Background(), "  ")
+	assert.ErrorContains(t, err, "empty command line")
+}
+
+// TestWithMockExecutor ensures that parallel tests can each use their
+// own mock executor through the context.
+func TestWithMockExecutor(t *testing.T) {
+	for _, name := range []string{"a", "b", "c"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := cmdexec.WithMockExecutor(context.Background(), t, cmdexec.NewMockExecutor(
+				(&cmdexec.MockCommand{Name: "echo", Stdout: []byte(name)}).Times(1),
+			))
+
+			out, err := cmdexec.CommandContext(ctx, "echo").Output()
+			assert.NilError(t, err)
+			assert.Equal(t, string(out), name)
+		})
+	}
+}
+
+// TestExecutor ensures that an Executor can be injected to use either
+// real or mocked commands without replacing the package's executor.
+func TestExecutor(t *testing.T) {
+	t.Parallel()
+
+	greet := func(e *cmdexec.Executor) string {
+		out, err := e.Command("echo", "hello").Output()
+		assert.NilError(t, err)
+		return string(out)
+	}
+
+	assert.Equal(t, greet(cmdexec.New()), "hello\n")
+	assert.Equal(t, greet(cmdexec.NewMockExecutor(
+		&cmdexec.MockCommand{Name: "echo", Args: []string{"hello"}, Stdout: []byte("mocked")},
+	).Executor(t)), "mocked")
+
+	cmd, err := cmdexec.New().CommandString(context.Background(), "echo 'a b'")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, cmd.Argv(), []string{"echo", "a b"})
+}
+
+// TestExecutorWithChaos ensures that failures are injected into
+// commands as configured, reproducibly for the same seed.
+func TestExecutorWithChaos(t *testing.T) {
+	t.Parallel()
+
+	mock := cmdexec.NewMockExecutor(&cmdexec.MockCommand{Name: "cat", Stdout: []byte("abcdef")}).Executor(t)
+
+	out, err := mock.WithChaos(cmdexec.Chaos{Probability: 1, Failures: []cmdexec.ChaosFailure{cmdexec.ChaosTruncate}}).Command("cat").Output()
+	assert.ErrorIs(t, err, cmdexec.ErrOutputTruncated)
+	assert.Equal(t, string(out), "abc")
+
+	err = mock.WithChaos(cmdexec.Chaos{Probability: 1, Failures: []cmdexec.ChaosFailure{cmdexec.ChaosExit}, ExitStatus: 2}).Command("cat").Run()
+	assert.Error(t, err, "exit status 2: cmdexec: chaos: injected failure")
+
+	err = mock.WithChaos(cmdexec.Chaos{Probability: 1, Failures: []cmdexec.ChaosFailure{cmdexec.ChaosTimeout}}).Command("cat").Run()
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded), err)
+
+	outcomes := func(seed uint64) []string {
+		chaos := mock.WithChaos(cmdexec.Chaos{Probability: 0.5, Seed: seed})
+
+		var outcomes []string
+		for range 20 {
+			out, err := chaos.Command("cat").Output()
+			outcomes = append(outcomes, fmt.Sprint(string(out), err))
+		}
+		return outcomes
+	}
+	assert.DeepEqual(t, outcomes(42), outcomes(42))
+	assert.Assert(t, slices.Contains(outcomes(42), "abcdef<nil>"))
+	assert.Assert(t, !slices.Equal(outcomes(42), outcomes(7)))
+}
+
+// TestMockCommandTimes ensures that run count expectations are verified
+// once the test has finished.
+func TestMockCommandTimes(t *testing.T) {
+	subT := mockt.New()
+
+	cmdexec.UseMockExecutor(subT, cmdexec.NewMockExecutor(
+		(&cmdexec.MockCommand{Name: "git", Args: []string{"fetch"}}).Times(3),
+		(&cmdexec.MockCommand{Name: "git", Args: []string{"push"}}).AtLeast(1),
+		(&cmdexec.MockCommand{Name: "git", Args: []string{"pull"}}).AtMost(1),
+		(&cmdexec.MockCommand{Name: "git", Args: []string{"status"}}).AtLeast(1).AtMost(2),
+	))
+
+	for range 2 {
+		assert.NilError(t, cmdexec.Command("git", "fetch").Run())
+		assert.NilError(t, cmdexec.Command("git", "pull").Run())
+		assert.NilError(t, cmdexec.Command("git", "status").Run())
+	}
+	subT.RunCleanup()
+
+	assert.Equal(t, subT.Failed(), true, "expected sub-test to fail")
+	assert.DeepEqual(t, subT.Errors(), []string{
+		"cmdexec: expected 'git fetch' to run exactly 3 times, but it ran 2 times",
+		"cmdexec: expected 'git pull' to run at most 1 times, but it ran 2 times",
+		"cmdexec: expected 'git push' to run at least 1 times, but it ran 0 times",
+	})
+}
+
+// TestMockCommandUses ensures that running a command more often than
+// it may be run fails, both when starting it and once the test has
+// finished.
+func TestMockCommandUses(t *testing.T) {
+	subT := mockt.New()
+
+	cmdexec.UseMockExecutor(subT, cmdexec.NewMockExecutor(
+		(&cmdexec.MockCommand{Name: "terraform", Args: []string{"apply"}, Stdout: []byte("Apply complete!")}).Uses(1),
+	))
+
+	out, err := cmdexec.Command("terraform", "apply").Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "Apply complete!")
+
+	_, err = cmdexec.Command("terraform", "apply").Output()
+	assert.Assert(t, errors.Is(err, cmdexec.ErrMockExhausted))
+	assert.Error(t, err, "cmdexec: mock exhausted: 'terraform apply' may only be run 1 times")
+	subT.RunCleanup()
+
+	assert.Equal(t, subT.Failed(), true, "expected sub-test to fail")
+	assert.DeepEqual(t, subT.Errors(), []string{
+		"cmdexec: mock exhausted: 'terraform apply' may only be run 1 times, but was run 1 more times",
+	})
+}
+
+// TestMockExecutorRequireAllUsed ensures that commands that were never
+// run fail the test when RequireAllUsed is set.
+func TestMockExecutorRequireAllUsed(t *testing.T) {
+	subT := mockt.New()
+
+	cmdexec.UseMockExecutor(subT, cmdexec.NewMockExecutor(
+		&cmdexec.MockCommand{Name: "git", Args: []string{"fetch"}},
+		&cmdexec.MockCommand{Name: "git", Args: []string{"push"}},
+		&cmdexec.MockCommand{Name: "git", Args: []string{"pull"}},
+		(&cmdexec.MockCommand{Name: "git", Args: []string{"status"}}).AtMost(1),
+	).RequireAllUsed())
+
+	assert.NilError(t, cmdexec.Command("git", "fetch").Run())
+	subT.RunCleanup()
+
+	assert.Equal(t, subT.Failed(), true, "expected sub-test to fail")
+	assert.DeepEqual(t, subT.Errors(), []string{
+		"cmdexec: registered commands were never run:\n\tgit pull\n\tgit push",
+	})
+}
+
+// TestMockExecutorAssertNoCommands ensures that AssertNoCommands only
+// fails the test if a command was run.
+func TestMockExecutorAssertNoCommands(t *testing.T) {
+	mock := cmdexec.NewMockExecutor(
+		&cmdexec.MockCommand{Name: "terraform", Args: []string{"apply"}},
+	)
+	cmdexec.UseMockExecutor(t, mock)
+
+	subT := mockt.New()
+	mock.AssertNoCommands(subT)
+	assert.Equal(t, subT.Failed(), false, "expected sub-test to pass")
+
+	assert.NilError(t, cmdexec.Command("terraform", "apply").Run())
+	mock.AssertNoCommands(subT)
+	assert.Equal(t, subT.Failed(), true, "expected sub-test to fail")
+	assert.DeepEqual(t, subT.Errors(), []string{
+		"cmdexec: expected no commands to be run, but ran:\n\tterraform apply",
+	})
+
+	// Commands that failed to start were still attempted.
+	mock = cmdexec.NewMockExecutor(
+		(&cmdexec.MockCommand{Name: "terraform", Args: []string{"plan"}}).Uses(0),
+		&cmdexec.MockCommand{Name: "terraform", Args: []string{"init"}, NotFound: true},
+	).ErrorOnUnregistered()
+	mock.Scenario().Step(&cmdexec.MockCommand{Name: "terraform", Args: []string{"apply"}}).Requires("plan")
+	// Exhausting a mock is reported once the test has finished.
+	e := mock.Executor(mockt.New())
+
+	assert.ErrorIs(t, e.Command("terraform", "destroy").Run(), cmdexec.ErrNotRegistered)
+	assert.ErrorIs(t, e.Command("terraform", "plan").Run(), cmdexec.ErrMockExhausted)
+	assert.ErrorIs(t, e.Command("terraform", "init").Run(), exec.ErrNotFound)
+	assert.ErrorIs(t, e.Command("terraform", "apply").Run(), cmdexec.ErrScenario)
+
+	subT = mockt.New()
+	mock.AssertNoCommands(subT)
+	assert.DeepEqual(t, subT.Errors(), []string{
+		"cmdexec: expected no commands to be run, but ran:\n\tterraform destroy\n\tterraform plan\n\tterraform init\n\tterraform apply",
+	})
+}
+
+// TestMockCommandOrder ensures that After allows unconstrained commands
+// and later runs in any order, while InOrder rejects interleaving.
+func TestMockCommandOrder(t *testing.T) {
+	t.Run("After", func(t *testing.T) {
+		subT := mockt.New()
+		commit := &cmdexec.MockCommand{Name: "git", Args: []string{"commit"}}
+		push := (&cmdexec.MockCommand{Name: "git", Args: []string{"push"}}).After(commit)
+		cmdexec.UseMockExecutor(subT, cmdexec.NewMockExecutor(commit, push,
+			&cmdexec.MockCommand{Name: "git", Args: []string{"status"}},
+			(&cmdexec.MockCommand{Name: "git", Args: []string{"tag"}}).After(push),
+		))
+
+		for _, arg := range []string{"status", "commit", "push", "commit", "tag"} {
+			assert.NilError(t, cmdexec.Command("git", arg).Run())
+		}
+		subT.RunCleanup()
+		assert.Equal(t, subT.Failed(), false, "expected sub-test to pass: %v", subT.Errors())
+	})
+
+	t.Run("InOrder", func(t *testing.T) {
+		subT := mockt.New()
+		initCmd := &cmdexec.MockCommand{Name: "terraform", Args: []string{"init"}}
+		plan := &cmdexec.MockCommand{Name: "terraform", Args: []string{"plan"}}
+		apply := &cmdexec.MockCommand{Name: "terraform", Args: []string{"apply"}}
+		mock := cmdexec.NewMockExecutor(initCmd, plan, apply)
+		cmdexec.UseMockExecutor(subT, mock.InOrder(initCmd, plan, apply))
+
+		for _, arg := range []string{"init", "plan", "init"} {
+			assert.NilError(t, cmdexec.Command("terraform", arg).Run())
+		}
+		subT.RunCleanup()
+		assert.Equal(t, subT.Failed(), true, "expected sub-test to fail")
+		assert.DeepEqual(t, subT.Errors(), []string{
+			"cmdexec: expected every run of 'terraform init' to be before 'terraform plan', but it ran after it",
+		})
+	})
+
+	t.Run("NeverRan", func(t *testing.T) {
+		subT := mockt.New()
+		build := &cmdexec.MockCommand{Name: "make", Args: []string{"build"}}
+		cmdexec.UseMockExecutor(subT, cmdexec.NewMockExecutor(build,
+			(&cmdexec.MockCommand{Name: "make", Args: []string{"deploy"}}).After(build),
+		))
+
+		assert.NilError(t, cmdexec.Command("make", "deploy").Run())
+		subT.RunCleanup()
+		assert.DeepEqual(t, subT.Errors(), []string{
+			"cmdexec: expected 'make deploy' to run after 'make build', but 'make build' never ran",
+		})
+	})
+}
@@ -0,0 +1,112 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"errors"
+	"strings"
+)
+
+// errEmptyCommandLine is returned when a command line contains no
+// words.
+var errEmptyCommandLine = errors.New("cmdexec: empty command line")
+
+// splitCommandLine splits the provided command line into words using
+// the POSIX shell rules for quoting: words are separated by unquoted
+// whitespace, single quotes preserve everything they contain, double
+// quotes preserve everything except backslash escapes of '$', '`',
+// '"', '\' and newlines, and an unquoted backslash preserves the
+// following character. No expansions (variables, globs, etc.) are
+// performed.
+func splitCommandLine(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case ch == '\\':
+			i++
+			if i == len(s) {
+				return nil, errors.New("cmdexec: unterminated escape in command line")
+			}
+			// A backslash followed by a newline is a line continuation.
+			if s[i] != '\n' {
+				word.WriteByte(s[i])
+				inWord = true
+			}
+		case ch == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end == -1 {
+				return nil, errors.New("cmdexec: unterminated single quote in command line")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case ch == '"':
+			inWord = true
+			for i++; ; i++ {
+				if i == len(s) {
+					return nil, errors.New("cmdexec: unterminated double quote in command line")
+				}
+				if s[i] == '"' {
+					break
+				}
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) != -1 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(s[i])
+			}
+		default:
+			word.WriteByte(ch)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	if len(words) == 0 {
+		return nil, errEmptyCommandLine
+	}
+	return words, nil
+}
+
+// joinCommandLine joins words into a command line that is split back
+// into the same words by splitCommandLine, quoting words with single
+// quotes where needed.
+func joinCommandLine(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		if word != "" && strings.Trim(word, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-+=/.,:@%") == "" {
+			quoted[i] = word
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
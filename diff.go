@@ -0,0 +1,215 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around every
+// change in a unified diff.
+const diffContext = 3
+
+// maxDiffCells is the maximum size of the table used to find the
+// longest common subsequence of lines, around 8 MiB. Larger inputs are
+// diffed by replacing all lines that differ, see diffLines.
+const maxDiffCells = 1 << 20
+
+// diffOp is a line of a diff, where kind is ' ' for an unchanged line,
+// '-' for a removed line, and '+' for an added line.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// mismatch returns a description of how got differs from want,
+// prefixed by msg. Single lines are quoted inline, while anything else
+// is shown as a unified diff so that large inputs stay readable.
+func mismatch(msg string, want, got []byte) string {
+	if !isMultiline(want) && !isMultiline(got) {
+		return fmt.Sprintf("%s to be %q but got %q", msg, want, got)
+	}
+
+	return fmt.Sprintf("%s to match, but it differs:\n%s", msg, unifiedDiff(want, got))
+}
+
+// isMultiline reports whether b contains more than one line.
+func isMultiline(b []byte) bool {
+	return bytes.IndexByte(bytes.TrimSuffix(b, []byte("\n")), '\n') != -1
+}
+
+// unifiedDiff returns a line-based unified diff from want to got.
+// Unchanged lines further than diffContext lines away from a change are
+// left out.
+func unifiedDiff(want, got []byte) string {
+	ops := diffLines(splitLines(string(want)), splitLines(string(got)))
+
+	// The line numbers (zero-based) in want and got before each op.
+	wantPos, gotPos := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for i, op := range ops {
+		wantPos[i+1], gotPos[i+1] = wantPos[i], gotPos[i]
+		if op.kind != '+' {
+			wantPos[i+1]++
+		}
+		if op.kind != '-' {
+			gotPos[i+1]++
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("--- expected\n+++ got\n")
+	for start := 0; start < len(ops); {
+		// Find the next change, and the end of the hunk containing it,
+		// which is once there are enough unchanged lines to separate it
+		// from the next one.
+		change := start
+		for change < len(ops) && ops[change].kind == ' ' {
+			change++
+		}
+		if change == len(ops) {
+			break
+		}
+
+		end, unchanged := change, 0
+		for end < len(ops) && unchanged <= 2*diffContext {
+			if ops[end].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+			end++
+		}
+		end -= max(unchanged-diffContext, 0)
+		begin := max(change-diffContext, start)
+
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n",
+			hunkRange(wantPos[begin], wantPos[end]-wantPos[begin]),
+			hunkRange(gotPos[begin], gotPos[end]-gotPos[begin]),
+		)
+		for _, op := range ops[begin:end] {
+			b.WriteByte(op.kind)
+			b.WriteString(strings.TrimSuffix(op.line, "\n"))
+			b.WriteByte('\n')
+			if !strings.HasSuffix(op.line, "\n") {
+				b.WriteString("\\ No newline at end of file\n")
+			}
+		}
+
+		start = end
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// hunkRange formats the range of a hunk starting after line pos
+// (zero-based) spanning n lines.
+func hunkRange(pos, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", pos)
+	}
+	if n == 1 {
+		return fmt.Sprint(pos + 1)
+	}
+	return fmt.Sprintf("%d,%d", pos+1, n)
+}
+
+// splitLines splits s into lines, keeping their trailing newlines.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the operations turning a into b, based on their
+// longest common subsequence of lines. If the lines between their
+// common prefix and suffix are too many to compare (see maxDiffCells),
+// all of them are shown as removed and added instead.
+func diffLines(a, b []string) []diffOp {
+	// Skip the common prefix and suffix, which are usually most of the
+	// lines, to keep the table in lcsDiff small.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if (len(ma)+1)*(len(mb)+1) <= maxDiffCells {
+		ops = lcsDiff(ops, ma, mb)
+	} else {
+		for _, line := range ma {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range mb {
+			ops = append(ops, diffOp{'+', line})
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// lcsDiff appends the operations turning a into b to ops, based on
+// their longest common subsequence of lines.
+func lcsDiff(ops []diffOp, a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	return ops
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"path"
+	"runtime"
+	"strings"
+)
+
+// mergeEnv returns env with the provided "key=value" pairs merged into
+// it. Later keys take precedence over earlier ones, while keeping the
+// position of their first occurrence. Like the operating system, keys
+// are case-insensitive on Windows.
+func mergeEnv(env []string, kv ...string) []string {
+	merged := make([]string, 0, len(env)+len(kv))
+	index := make(map[string]int, len(env)+len(kv))
+	for _, e := range append(env[:len(env):len(env)], kv...) {
+		key := envKey(e)
+		if runtime.GOOS == "windows" {
+			key = strings.ToUpper(key)
+		}
+
+		if i, ok := index[key]; ok {
+			merged[i] = e
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+// scrubEnv returns env without the variables whose keys match any of
+// the provided patterns, see [path.Match] for the pattern syntax. Like
+// the operating system, keys are case-insensitive on Windows.
+func scrubEnv(env []string, patterns ...string) []string {
+	scrubbed := make([]string, 0, len(env))
+	for _, e := range env {
+		if !matchEnvKey(envKey(e), patterns) {
+			scrubbed = append(scrubbed, e)
+		}
+	}
+	return scrubbed
+}
+
+// envKey returns the key of the "key=value" pair e. Like os/exec, a
+// leading "=" is part of the key, as used by Windows for the working
+// directory of each drive (e.g., "=C:=C:\src").
+func envKey(e string) string {
+	if rest, ok := strings.CutPrefix(e, "="); ok {
+		key, _, _ := strings.Cut(rest, "=")
+		return "=" + key
+	}
+	key, _, _ := strings.Cut(e, "=")
+	return key
+}
+
+// matchEnvKey returns true if key matches any of the provided patterns.
+// Malformed patterns only match themselves.
+func matchEnvKey(key string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if runtime.GOOS == "windows" {
+			key, pattern = strings.ToUpper(key), strings.ToUpper(pattern)
+		}
+
+		if ok, err := path.Match(pattern, key); ok || (err != nil && pattern == key) {
+			return true
+		}
+	}
+	return false
+}
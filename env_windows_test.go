@@ -0,0 +1,29 @@
+package cmdexec_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jaredallard/cmdexec"
+	"gotest.tools/v3/assert"
+)
+
+// TestAppendEnvKeepsDriveDirectories ensures that the hidden variables
+// Windows uses for the working directory of each drive aren't merged
+// into a single one, and that keys are merged case-insensitively.
+func TestAppendEnvKeepsDriveDirectories(t *testing.T) {
+	for _, cmd := range []cmdexec.Cmd{cmdexec.Command("cmd"), &cmdexec.MockCommand{}} {
+		cmd.SetEnviron([]string{`=C:=C:\src`, `=D:=D:\data`, `Path=C:\Windows`})
+		cmd.AppendEnv(`=D:=D:\tmp`, `PATH=C:\bin`)
+
+		// The standard executor may add variables required by Windows,
+		// e.g., SYSTEMROOT.
+		env := cmd.Environ()
+		for _, e := range []string{`=C:=C:\src`, `=D:=D:\tmp`, `PATH=C:\bin`} {
+			assert.Assert(t, slices.Contains(env, e), "%q not in %q", e, env)
+		}
+		for _, e := range []string{`=D:=D:\data`, `Path=C:\Windows`} {
+			assert.Assert(t, !slices.Contains(env, e), "%q in %q", e, env)
+		}
+	}
+}
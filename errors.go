@@ -0,0 +1,134 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// ErrOutputTruncated is returned by [Cmd.Output] and
+// [Cmd.CombinedOutput] when the output of a command exceeded the limit
+// set through [Cmd.SetMaxOutputBytes], or was cut short by
+// [ChaosTruncate]. The truncated output is still returned.
+var ErrOutputTruncated = errors.New("cmdexec: output truncated")
+
+// ErrNotRegistered is returned when running a command that wasn't
+// registered with the [MockExecutor] in use, see
+// [MockExecutor.ErrorOnUnregistered].
+var ErrNotRegistered = errors.New("cmdexec: no command registered")
+
+// ErrMockExhausted is returned when starting a mocked command that has
+// already been run as many times as it may be, see [MockCommand.Uses].
+var ErrMockExhausted = errors.New("cmdexec: mock exhausted")
+
+// ErrScenario is returned when starting a command of a [Scenario] that
+// isn't allowed in its current state, e.g., "git commit" before "git
+// init".
+var ErrScenario = errors.New("cmdexec: command not allowed by scenario")
+
+// withTruncated returns err wrapped with [ErrOutputTruncated] if
+// truncated is true.
+func withTruncated(err error, truncated bool) error {
+	switch {
+	case !truncated:
+		return err
+	case err == nil:
+		return ErrOutputTruncated
+	default:
+		return fmt.Errorf("%w: %w", ErrOutputTruncated, err)
+	}
+}
+
+// ExitError is returned by both the standard and mock executors when a
+// command exits with a non-zero exit code. Unlike [exec.ExitError], it
+// always carries the stderr output of the command when it is known,
+// and includes it in the error message.
+type ExitError struct {
+	// Command is the command line of the command that failed, see
+	// [Cmd.String].
+	Command string
+
+	// Code is the exit code of the command, or -1 if it was terminated
+	// by a signal.
+	Code int
+
+	// Stderr contains the stderr output of the command, if it was
+	// captured (e.g., by [Cmd.Output]).
+	Stderr []byte
+
+	// Err is the underlying error, if any. When using the standard
+	// executor, this is an [*exec.ExitError].
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ExitError) Error() string {
+	msg := fmt.Sprintf("exit status %d", e.Code)
+	if e.Err != nil {
+		msg = e.Err.Error()
+	}
+
+	if stderr := bytes.TrimSpace(e.Stderr); len(stderr) > 0 {
+		msg += ": " + string(stderr)
+	}
+
+	return msg
+}
+
+// Unwrap returns the underlying error, allowing [errors.As] to be used
+// to retrieve an [*exec.ExitError] when using the standard executor.
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of the command, matching
+// [exec.ExitError.ExitCode].
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// wrapExitError wraps err in an [ExitError] if it is an
+// [*exec.ExitError], otherwise it is returned as-is.
+func wrapExitError(command string, err error) error {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return err
+	}
+
+	var execErr *exec.ExitError
+	if !errors.As(err, &execErr) {
+		return err
+	}
+
+	return &ExitError{
+		Command: command,
+		Code:    execErr.ExitCode(),
+		Stderr:  execErr.Stderr,
+		Err:     err,
+	}
+}
+
+// newTimeoutError returns the error returned when a command doesn't
+// finish within the timeout set through [Cmd.SetTimeout].
+func newTimeoutError(timeout time.Duration) error {
+	return fmt.Errorf("cmdexec: command timed out after %s: %w", timeout, context.DeadlineExceeded)
+}
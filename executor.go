@@ -19,6 +19,8 @@ package cmdexec
 import (
 	"context"
 	"sync"
+
+	"github.com/jaredallard/cmdexec/internal/mockt"
 )
 
 // Contains package globals to control which executor is used by the
@@ -39,3 +41,141 @@ var (
 // executorFn is a function that returns a new Cmd based on the given
 // arguments.
 type executorFn func(context.Context, string, ...string) Cmd
+
+// Executor creates commands, allowing libraries to accept one through
+// dependency injection instead of relying on the package-level
+// functions (e.g., [Command]), which use the executor returned by
+// [Default]. Use [New] for one that executes commands for real and
+// [MockExecutor.Executor] for one that uses mocked commands.
+type Executor struct {
+	// fn creates commands. If nil, the executor of the package is used,
+	// which can be replaced through [UseMockExecutor].
+	fn executorFn
+}
+
+// Commander creates commands. It is implemented by [Executor] and can
+// be accepted instead of one by code that should also work with other
+// implementations, e.g., the generated mocks in the mocks package.
+type Commander interface {
+	// Command returns a new Cmd, see [Command].
+	Command(name string, arg ...string) Cmd
+
+	// CommandContext returns a new Cmd, see [CommandContext].
+	CommandContext(ctx context.Context, name string, arg ...string) Cmd
+
+	// CommandString returns a new Cmd for the provided command line,
+	// see [CommandString].
+	CommandString(ctx context.Context, cmdline string) (Cmd, error)
+}
+
+// Ensure that [Executor] implements [Commander].
+var _ Commander = (*Executor)(nil)
+
+// defaultExecutor is the executor used by the package-level functions.
+var defaultExecutor = &Executor{}
+
+// New returns an [Executor] that executes commands for real.
+func New() *Executor {
+	return &Executor{fn: stdExecutor}
+}
+
+// Default returns the [Executor] used by the package-level functions,
+// which uses the mock executor set through [UseMockExecutor] (or
+// carried by the context, see [WithMockExecutor]), if any.
+func Default() *Executor {
+	return defaultExecutor
+}
+
+// Command returns a new Cmd that will call the given command with the
+// given arguments, see [Command].
+func (e *Executor) Command(name string, arg ...string) Cmd {
+	return e.CommandContext(context.Background(), name, arg...)
+}
+
+// CommandContext returns a new Cmd that will call the given command
+// with the given arguments and the given context, see
+// [CommandContext].
+func (e *Executor) CommandContext(ctx context.Context, name string, arg ...string) Cmd {
+	var cmd Cmd
+	if e.fn != nil {
+		cmd = e.fn(ctx, name, arg...)
+	} else {
+		cmd = newCommand(ctx, name, arg...)
+	}
+
+	applyDefaultCancelSignal(cmd)
+	return cmd
+}
+
+// CommandString returns a new Cmd for the provided command line and
+// the given context, see [CommandString].
+func (e *Executor) CommandString(ctx context.Context, cmdline string) (Cmd, error) {
+	words, err := splitCommandLine(cmdline)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.CommandContext(ctx, words[0], words[1:]...), nil
+}
+
+// Executor returns an [Executor] that creates commands using the mock
+// executor, without replacing the executor used by the rest of the
+// package (see [UseMockExecutor]). Like UseMockExecutor, the
+// expectations set on the registered commands are verified once the
+// test has finished.
+func (e *MockExecutor) Executor(t mockt.T) *Executor {
+	t.Cleanup(func() {
+		e.verify(t)
+	})
+	return &Executor{fn: e.executor}
+}
+
+// executorKey is the context key of the executor set by
+// [WithMockExecutor].
+type executorKey struct{}
+
+// newCommand creates a new Cmd using the executor carried by ctx, if
+// any, or the package's executor otherwise.
+func newCommand(ctx context.Context, name string, arg ...string) Cmd {
+	if fn, ok := ctx.Value(executorKey{}).(executorFn); ok {
+		return fn(ctx, name, arg...)
+	}
+
+	executorRLock.Lock()
+	defer executorRLock.Unlock()
+	return executor(ctx, name, arg...)
+}
+
+// useExecutor replaces the executor used by cmdexec with fn until the
+// test has finished, at which point done is called before the original
+// executor is restored. caller is the name of the exported function
+// replacing the executor, used when the test fails.
+func useExecutor(t mockt.T, caller string, fn executorFn, done func()) {
+	// Prevent new executors from being used until this test has finished.
+	if !executorWLock.TryLock() {
+		t.Fatal(caller + " can only be called once per test")
+		return
+	}
+
+	// Lock the reader to prevent new commands from being created while we
+	// swap out the executor.
+	executorRLock.Lock()
+	originalExecutor := executor
+	executor = fn
+	executorRLock.Unlock()
+
+	t.Cleanup(func() {
+		done()
+
+		// Lock the reader again to prevent new commands from being created
+		// while we restore the original executor.
+		executorRLock.Lock()
+
+		// Unlock the reader and writer once we're done.
+		defer executorRLock.Unlock()
+		defer executorWLock.Unlock()
+
+		// Restore the original executor.
+		executor = originalExecutor
+	})
+}
@@ -0,0 +1,225 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jaredallard/cmdexec/internal/mockt"
+)
+
+// Times sets the number of times the command is expected to run. The
+// expectation is verified once the test that called [UseMockExecutor]
+// has finished. It returns the command to allow chaining, e.g.:
+//
+//	mock.AddCommand((&cmdexec.MockCommand{Name: "git", Args: []string{"fetch"}}).Times(3))
+func (c *MockCommand) Times(n int) *MockCommand {
+	return c.AtLeast(n).AtMost(n)
+}
+
+// AtLeast sets the minimum number of times the command is expected to
+// run, see [MockCommand.Times].
+func (c *MockCommand) AtLeast(n int) *MockCommand {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	c.atLeast = &n
+	return c
+}
+
+// AtMost sets the maximum number of times the command is expected to
+// run, see [MockCommand.Times].
+func (c *MockCommand) AtMost(n int) *MockCommand {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	c.atMost = &n
+	return c
+}
+
+// Uses limits the number of times the command may be run to n. Once
+// used up, starting the command fails with [ErrMockExhausted] instead of
+// returning the same output again, and the test that called
+// [UseMockExecutor] fails once it has finished. This catches accidental
+// duplicate executions, e.g., running "terraform apply" twice. It
+// returns the command to allow chaining.
+func (c *MockCommand) Uses(n int) *MockCommand {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	c.uses = &n
+	return c
+}
+
+// RequireAllUsed makes the test that called [UseMockExecutor] fail if
+// any of the registered commands was never run by the time it has
+// finished. Commands with an explicit expectation (e.g.,
+// [MockCommand.AtMost]) are only checked against that expectation. It
+// returns the executor to allow chaining.
+func (e *MockExecutor) RequireAllUsed() *MockExecutor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.requireAllUsed = true
+	return e
+}
+
+// AssertNoCommands reports an error to t if any command was run through
+// the executor so far, e.g., to ensure that a dry-run mode doesn't
+// execute anything. Commands that failed to start are reported too,
+// e.g., unregistered commands (see [MockExecutor.ErrorOnUnregistered]).
+// Commands that were passed through (see [MockExecutor.Passthrough])
+// aren't recorded and thus not reported.
+func (e *MockExecutor) AssertNoCommands(t mockt.T) {
+	e.callsMu.Lock()
+	attempts := slices.Clone(e.attempts)
+	e.callsMu.Unlock()
+	if len(attempts) == 0 {
+		return
+	}
+
+	ran := make([]string, 0, len(attempts))
+	for _, argv := range attempts {
+		ran = append(ran, strings.Join(argv, " "))
+	}
+	t.Errorf("cmdexec: expected no commands to be run, but ran:\n\t%s", strings.Join(ran, "\n\t"))
+}
+
+// hasExpectation returns true if the command has an explicit run count
+// expectation.
+func (c *MockCommand) hasExpectation() bool {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	return c.atLeast != nil || c.atMost != nil
+}
+
+// runCount returns the number of times the command has been started.
+func (c *MockCommand) runCount() int {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	return c.runs
+}
+
+// verifyTimes reports an error to t if the command didn't run the
+// expected number of times.
+func (c *MockCommand) verifyTimes(t mockt.T) {
+	c.statsMu.Lock()
+	runs, atLeast, atMost := c.runs, c.atLeast, c.atMost
+	c.statsMu.Unlock()
+
+	var expected string
+	switch {
+	case atLeast != nil && atMost != nil && *atLeast == *atMost:
+		if runs == *atLeast {
+			return
+		}
+		expected = fmt.Sprintf("exactly %d", *atLeast)
+	case atLeast != nil && atMost != nil:
+		if runs >= *atLeast && runs <= *atMost {
+			return
+		}
+		expected = fmt.Sprintf("between %d and %d", *atLeast, *atMost)
+	case atLeast != nil:
+		if runs >= *atLeast {
+			return
+		}
+		expected = fmt.Sprintf("at least %d", *atLeast)
+	case atMost != nil:
+		if runs <= *atMost {
+			return
+		}
+		expected = fmt.Sprintf("at most %d", *atMost)
+	default:
+		return
+	}
+
+	t.Errorf("cmdexec: expected '%s' to run %s times, but it ran %d times", strings.Join(c.Argv(), " "), expected, runs)
+}
+
+// verifyUses reports an error to t if the command was started after it
+// was exhausted, see Uses.
+func (c *MockCommand) verifyUses(t mockt.T) {
+	c.statsMu.Lock()
+	uses, exhausted := c.uses, c.exhausted
+	c.statsMu.Unlock()
+
+	if exhausted > 0 {
+		t.Errorf("%v: '%s' may only be run %d times, but was run %d more times",
+			ErrMockExhausted, strings.Join(c.Argv(), " "), *uses, exhausted,
+		)
+	}
+}
+
+// verify reports an error to t for every command whose expectations
+// weren't met.
+func (e *MockExecutor) verify(t mockt.T) {
+	e.mu.RLock()
+	requireAllUsed, defaultCmd := e.requireAllUsed, e.defaultCmd
+	nameDefaults := slices.Collect(maps.Values(e.nameDefaults))
+	e.mu.RUnlock()
+
+	var unused []string
+	for _, cmd := range e.commands() {
+		cmd.verifyTimes(t)
+		cmd.verifyUses(t)
+		cmd.verifyOrder(t)
+
+		if requireAllUsed && !cmd.hasExpectation() && cmd.runCount() == 0 {
+			unused = append(unused, strings.Join(cmd.Argv(), " "))
+		}
+	}
+
+	for _, cmd := range append(nameDefaults, defaultCmd) {
+		if cmd != nil {
+			cmd.verifyTimes(t)
+			cmd.verifyUses(t)
+			cmd.verifyOrder(t)
+		}
+	}
+
+	if len(unused) > 0 {
+		t.Errorf("cmdexec: registered commands were never run:\n\t%s", strings.Join(unused, "\n\t"))
+	}
+}
+
+// commands returns all commands registered with the executor, sorted
+// by their command line.
+func (e *MockExecutor) commands() []*MockCommand {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var cmds []*MockCommand
+	for _, cmd := range e.cmds {
+		cmds = append(cmds, cmd)
+	}
+	for _, m := range e.matchers {
+		cmds = append(cmds, m.cmd)
+	}
+	for _, s := range e.scenarios {
+		cmds = append(cmds, s.commands()...)
+	}
+
+	slices.SortStableFunc(cmds, func(a, b *MockCommand) int {
+		return strings.Compare(strings.Join(a.Argv(), " "), strings.Join(b.Argv(), " "))
+	})
+	return slices.Compact(cmds)
+}
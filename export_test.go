@@ -0,0 +1,5 @@
+package cmdexec
+
+// ErrArgListTooLong exports errArgListTooLong for tests, as the
+// underlying errno doesn't exist on every platform.
+var ErrArgListTooLong = errArgListTooLong
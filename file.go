@@ -0,0 +1,95 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteMode controls how an existing file is written to by
+// [Cmd.SetStdoutFile] and [Cmd.SetStderrFile].
+type WriteMode int
+
+// Contains the supported write modes.
+const (
+	// WriteTruncate truncates the file before writing to it.
+	WriteTruncate WriteMode = iota
+
+	// WriteAppend appends to the end of the file.
+	WriteAppend
+)
+
+// outputFile is a file that the output of a command is written to.
+type outputFile struct {
+	path string
+	mode WriteMode
+}
+
+// open opens (creating, if necessary) the file for writing.
+func (f *outputFile) open() (*os.File, error) {
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if f.mode == WriteAppend {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	return os.OpenFile(f.path, flag, 0o644)
+}
+
+// openOutputFiles opens the provided stdout and stderr files, if set.
+// If both refer to the same path, the file is only opened once so that
+// writes don't overwrite each other. Files that are returned must be
+// closed with closeOutputFiles.
+func openOutputFiles(stdout, stderr *outputFile) (outF, errF *os.File, err error) {
+	if stdout != nil {
+		if outF, err = stdout.open(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if stderr != nil {
+		if stdout != nil && sameFile(stdout.path, stderr.path) {
+			return outF, outF, nil
+		}
+
+		if errF, err = stderr.open(); err != nil {
+			closeOutputFiles(outF, nil)
+			return nil, nil, err
+		}
+	}
+
+	return outF, errF, nil
+}
+
+// closeOutputFiles closes the files returned by openOutputFiles.
+func closeOutputFiles(outF, errF *os.File) {
+	if outF != nil {
+		outF.Close() //nolint:errcheck // Why: Best effort.
+	}
+	if errF != nil && errF != outF {
+		errF.Close() //nolint:errcheck // Why: Best effort.
+	}
+}
+
+// sameFile returns true if a and b refer to the same path.
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return absA == absB
+}
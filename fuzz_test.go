@@ -0,0 +1,153 @@
+package cmdexec
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+// fuzzSeeds are the seed corpus shared by the fuzz tests. Each seed is
+// a command name, its arguments separated by NUL bytes (see
+// fuzzArgv), and its stdin.
+var fuzzSeeds = []struct {
+	name, args, stdin string
+}{
+	{"git", "status", ""},
+	{"git", "commit\x00-m\x00a message", "hello world\n"},
+	{"git", "a b", ""},
+	{"git", "a\x00b", ""},
+	{"git", "", ""},
+	{"", "", ""},
+	{"echo", `'quoted'\x00"double"\x00back\slash\x00$HOME\x00*`, ""},
+	{"echo", "trailing\\\x00new\nline\x00\ttab", "no trailing newline"},
+
+	// Windows-style paths.
+	{`C:\Program Files\Git\bin\git.exe`, `--git-dir=C:\Users\me\repo\.git\x00status`, "\r\n"},
+	{`\\server\share\tool.exe`, `"C:\path with spaces\"\x00C:\trailing\`, ""},
+	{"cmd.exe", `/c\x00echo ^& %PATH% "a&b"`, ""},
+
+	// UTF-8 edge cases.
+	{"écho", "日本語\x00emoji 🎉\x00\u202eright-to-left", "ünïcödé\n"},
+	{"echo", "\xff\xfe invalid\x00\xc3", "\xff"},
+	{"echo", "\u00a0non-breaking\x00zero\u200bwidth\x00\ufeffbom", ""},
+}
+
+// fuzzArgv returns the name and arguments of a command from fuzzed
+// input, where args contains the arguments separated by NUL bytes. NUL
+// is used as the separator since it can't be part of an argument.
+func fuzzArgv(name, args string) (string, []string) {
+	if args == "" {
+		return name, nil
+	}
+	return name, strings.Split(args, "\x00")
+}
+
+// addFuzzSeeds adds fuzzSeeds to the seed corpus of f.
+func addFuzzSeeds(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed.name, seed.args, []byte(seed.stdin))
+	}
+}
+
+// FuzzCommandLine ensures that command lines joined by joinCommandLine
+// are split back into the same words.
+func FuzzCommandLine(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, name, args string, _ []byte) {
+		name, argv := fuzzArgv(name, args)
+		if strings.Contains(name, "\x00") {
+			t.Skip("NUL can't be part of a command name")
+		}
+
+		words := append([]string{name}, argv...)
+		got, err := splitCommandLine(joinCommandLine(words))
+		assert.NilError(t, err)
+		assert.DeepEqual(t, got, words)
+	})
+}
+
+// FuzzMockExecutor ensures that mocked commands are only used for the
+// exact name and arguments they were registered with.
+func FuzzMockExecutor(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, name, args string, stdin []byte) {
+		name, argv := fuzzArgv(name, args)
+
+		// Register commands that would collide with the fuzzed one if
+		// words weren't kept apart.
+		mock := NewMockExecutor(&MockCommand{Name: name, Args: argv, Stdin: stdin, Stdout: stdin})
+		for _, other := range [][]string{
+			{name, strings.Join(argv, " ")},
+			{name + " " + strings.Join(argv, " ")},
+			append([]string{name}, append(argv, "")...),
+		} {
+			if len(other) != len(argv)+1 || strings.Join(other, "\x00") != strings.Join(append([]string{name}, argv...), "\x00") {
+				mock.AddCommand(&MockCommand{Name: other[0], Args: other[1:], Stdout: []byte("collision")})
+			}
+		}
+
+		cmd := mock.executor(context.Background(), name, argv...)
+		cmd.SetStdinBytes(stdin)
+		out, err := cmd.Output()
+		assert.NilError(t, err)
+		assert.Equal(t, string(out), string(stdin))
+	})
+}
+
+// FuzzStdExecutor ensures that arguments and stdin are passed to a
+// real command unchanged. The command is this test binary running
+// TestFuzzHelperProcess, so nothing else is executed.
+func FuzzStdExecutor(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, _, args string, stdin []byte) {
+		_, argv := fuzzArgv("", args)
+
+		cmd := stdExecutor(context.Background(), os.Args[0], append([]string{"-test.run=^TestFuzzHelperProcess$", "--"}, argv...)...)
+		cmd.AppendEnv("CMDEXEC_FUZZ_HELPER=1")
+		cmd.SetStdinBytes(stdin)
+		out, err := cmd.Output()
+		assert.NilError(t, err)
+
+		// Arguments are sent as bytes since JSON strings can't contain
+		// invalid UTF-8.
+		var got struct {
+			Args  [][]byte
+			Stdin []byte
+		}
+		assert.NilError(t, json.Unmarshal(out, &got))
+		assert.Equal(t, len(got.Args), len(argv))
+		for i, arg := range argv {
+			assert.Equal(t, string(got.Args[i]), arg)
+		}
+		assert.Equal(t, string(got.Stdin), string(stdin))
+	})
+}
+
+// TestFuzzHelperProcess isn't a real test. It is run by
+// FuzzStdExecutor, writing the arguments and stdin it received to
+// stdout as JSON.
+func TestFuzzHelperProcess(t *testing.T) {
+	if os.Getenv("CMDEXEC_FUZZ_HELPER") != "1" {
+		t.Skip("only run by FuzzStdExecutor")
+	}
+
+	var args [][]byte
+	for i, arg := range os.Args {
+		if arg == "--" {
+			for _, arg := range os.Args[i+1:] {
+				args = append(args, []byte(arg))
+			}
+			break
+		}
+	}
+
+	stdin, err := io.ReadAll(os.Stdin)
+	assert.NilError(t, err)
+	assert.NilError(t, json.NewEncoder(os.Stdout).Encode(map[string]any{"Args": args, "Stdin": stdin}))
+	os.Exit(0)
+}
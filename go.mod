@@ -1,7 +1,18 @@
 module github.com/jaredallard/cmdexec
 
-go 1.20
+go 1.23
 
-require gotest.tools/v3 v3.5.1
+require (
+	github.com/creack/pty v1.1.24
+	github.com/stretchr/testify v1.9.0
+	go.uber.org/mock v0.5.2
+	gotest.tools/v3 v3.5.1
+)
 
-require github.com/google/go-cmp v0.5.9 // indirect
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/google/go-cmp v0.5.9 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/stretchr/objx v0.5.2 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -17,7 +17,10 @@
 // mockt implements a system for mocking [testing.T].
 package mockt
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
 type T interface {
 	// Failed returns if the test has failed or not, see
@@ -27,6 +30,9 @@ type T interface {
 	// Fatal is a wrapper around [testing.T.Fatal].
 	Fatal(args ...interface{})
 
+	// Errorf is a wrapper around [testing.T.Errorf].
+	Errorf(format string, args ...any)
+
 	// Cleanup is a wrapper around [testing.T.Cleanup].
 	Cleanup(func())
 }
@@ -40,6 +46,9 @@ type t struct {
 	// args are the failure arguments for [t.Fatal].
 	args []any
 
+	// errors are the messages passed to [t.Errorf].
+	errors []string
+
 	cleanup func()
 }
 
@@ -62,6 +71,17 @@ func (t *t) Fatal(args ...any) {
 	t.args = args
 }
 
+// Errorf implements [T.Errorf].
+func (t *t) Errorf(format string, args ...any) {
+	t.failed = true
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+// Errors returns the messages passed to [t.Errorf].
+func (t *t) Errors() []string {
+	return t.errors
+}
+
 // Cleanup implements [T.Cleanup].
 func (t *t) Cleanup(fn func()) { t.cleanup = fn }
 
@@ -0,0 +1,151 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// checkJSON checks that got is the same JSON value as want, see
+// [MockCommand.StdinJSON].
+func checkJSON(want, got []byte) error {
+	wantV, err := parseJSON(want)
+	if err != nil {
+		return fmt.Errorf("failed to parse Stdin as JSON: %w", err)
+	}
+
+	gotV, err := parseJSON(got)
+	if err != nil {
+		return fmt.Errorf("expected stdin set by SetStdin() to be JSON but failed to parse it: %w", err)
+	}
+
+	if diffs := diffJSON("$", wantV, gotV); len(diffs) > 0 {
+		return fmt.Errorf("expected stdin set by SetStdin() to match the JSON in Stdin, but it differs:\n\t%s", strings.Join(diffs, "\n\t"))
+	}
+	return nil
+}
+
+// parseJSON parses b as a single JSON value, keeping numbers as
+// [json.Number] so they can be compared without losing precision.
+func parseJSON(b []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+// diffJSON returns the differences between the JSON values want and
+// got, as parsed by parseJSON, one per line prefixed by the path to the
+// value that differs (e.g., "$.spec.replicas"). Object keys are
+// compared regardless of their order and numbers by their value, so
+// 1 and 1.0 are equal.
+func diffJSON(path string, want, got any) []string {
+	switch want := want.(type) {
+	case map[string]any:
+		got, ok := got.(map[string]any)
+		if !ok {
+			break
+		}
+
+		var diffs []string
+		for _, k := range sortedKeys(want, got) {
+			wantV, inWant := want[k]
+			gotV, inGot := got[k]
+			kpath := path + "." + k
+			switch {
+			case !inGot:
+				diffs = append(diffs, fmt.Sprintf("%s: missing, expected %s", kpath, encodeJSON(wantV)))
+			case !inWant:
+				diffs = append(diffs, fmt.Sprintf("%s: unexpected %s", kpath, encodeJSON(gotV)))
+			default:
+				diffs = append(diffs, diffJSON(kpath, wantV, gotV)...)
+			}
+		}
+		return diffs
+	case []any:
+		got, ok := got.([]any)
+		if !ok {
+			break
+		}
+
+		var diffs []string
+		for i := range max(len(want), len(got)) {
+			ipath := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= len(got):
+				diffs = append(diffs, fmt.Sprintf("%s: missing, expected %s", ipath, encodeJSON(want[i])))
+			case i >= len(want):
+				diffs = append(diffs, fmt.Sprintf("%s: unexpected %s", ipath, encodeJSON(got[i])))
+			default:
+				diffs = append(diffs, diffJSON(ipath, want[i], got[i])...)
+			}
+		}
+		return diffs
+	case json.Number:
+		if got, ok := got.(json.Number); ok && numbersEqual(want, got) {
+			return nil
+		}
+	default:
+		if want == got {
+			return nil
+		}
+	}
+
+	return []string{fmt.Sprintf("%s: expected %s but got %s", path, encodeJSON(want), encodeJSON(got))}
+}
+
+// numbersEqual reports whether a and b are the same number.
+func numbersEqual(a, b json.Number) bool {
+	x, okX := new(big.Rat).SetString(string(a))
+	y, okY := new(big.Rat).SetString(string(b))
+	return okX && okY && x.Cmp(y) == 0
+}
+
+// sortedKeys returns the keys of all of maps, sorted and deduplicated.
+func sortedKeys(maps ...map[string]any) []string {
+	var keys []string
+	for _, m := range maps {
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// encodeJSON returns v encoded as JSON, for use in error messages.
+func encodeJSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,162 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+// lineWriter is an [io.Writer] that calls fn for every line written to
+// it, without the trailing newline. A final line that isn't terminated
+// by a newline is only passed to fn once Flush is called.
+type lineWriter struct {
+	fn func(string)
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// Write implements [io.Writer].
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i == -1 {
+			break
+		}
+		w.fn(strings.TrimSuffix(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush calls fn with any remaining unterminated line.
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.fn(strings.TrimSuffix(string(w.buf), "\r"))
+		w.buf = nil
+	}
+}
+
+// limitedBuffer is a buffer that holds at most max bytes, discarding
+// the rest. Writes never fail so that the command isn't interrupted. A
+// max of zero or less disables the limit.
+type limitedBuffer struct {
+	// buf isn't embedded so that [io.Copy] can't bypass Write through
+	// [bytes.Buffer.ReadFrom].
+	buf       bytes.Buffer
+	max       int64
+	truncated bool
+}
+
+// Write implements [io.Writer].
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.max > 0 {
+		if remaining := b.max - int64(b.buf.Len()); int64(len(p)) > remaining {
+			p = p[:max(remaining, 0)]
+			b.truncated = true
+		}
+	}
+
+	b.buf.Write(p) //nolint:errcheck // Why: Never fails.
+	return n, nil
+}
+
+// Bytes returns the buffered bytes.
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// lockedWriter is an [io.Writer] that serializes writes to w. It is
+// used when stdout and stderr share a writer that would otherwise be
+// written to concurrently.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write implements [io.Writer].
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.w.Write(p)
+}
+
+// multiWriter returns a writer that writes to w, if not nil, and to
+// all of extra.
+func multiWriter(w io.Writer, extra ...io.Writer) io.Writer {
+	if w != nil {
+		extra = append([]io.Writer{w}, extra...)
+	}
+	if len(extra) == 1 {
+		return extra[0]
+	}
+	return io.MultiWriter(extra...)
+}
+
+// interfaceEqual protects against panics from doing equality tests on
+// two interfaces with non-comparable underlying types.
+func interfaceEqual(a, b any) bool {
+	defer func() {
+		recover() //nolint:errcheck // Why: Non-comparable types aren't equal.
+	}()
+	return a == b
+}
+
+// crlfWriter translates newlines written to w into "\r\n", like a
+// terminal does for output by default. Newlines already preceded by
+// '\r' are left as-is.
+type crlfWriter struct {
+	w      io.Writer
+	lastCR bool
+}
+
+// Write implements [io.Writer].
+func (c *crlfWriter) Write(b []byte) (int, error) {
+	out := make([]byte, 0, len(b))
+	for _, ch := range b {
+		if ch == '\n' && !c.lastCR {
+			out = append(out, '\r')
+		}
+		out = append(out, ch)
+		c.lastCR = ch == '\r'
+	}
+
+	if _, err := c.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// toCRLF returns b with its newlines translated like [crlfWriter]
+// does.
+func toCRLF(b []byte) []byte {
+	var out bytes.Buffer
+	(&crlfWriter{w: &out}).Write(b) //nolint:errcheck // Why: Writing to a buffer never fails.
+	return out.Bytes()
+}
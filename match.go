@@ -0,0 +1,145 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import "slices"
+
+// Matcher matches the commands a [MockCommand] is used for, see
+// [MockExecutor.AddMatch].
+type Matcher interface {
+	// Match reports whether a command with the provided name and
+	// arguments matches.
+	Match(name string, args []string) bool
+}
+
+// MatcherFunc is an adapter to allow the use of ordinary functions as a
+// [Matcher].
+type MatcherFunc func(name string, args []string) bool
+
+// Match implements [Matcher] by calling f(name, args).
+func (f MatcherFunc) Match(name string, args []string) bool {
+	return f(name, args)
+}
+
+// mockMatcher is a [MockCommand] that is matched by a [Matcher] instead
+// of by its exact name and arguments.
+type mockMatcher struct {
+	matcher Matcher
+	cmd     *MockCommand
+}
+
+// argsMatcher returns the function used to match the arguments of
+// commands against c, or nil if they must be equal to its Args.
+func (c *MockCommand) argsMatcher() func(args []string) bool {
+	switch {
+	case c.AnyArgs:
+		return func([]string) bool { return true }
+	case c.ArgPatterns != nil:
+		return c.matchArgPatterns
+	case c.UnorderedArgs:
+		return c.matchUnorderedArgs
+	case slices.Contains(c.Args, Any):
+		return c.matchAnyArgs
+	}
+	return nil
+}
+
+// matchArgPatterns reports whether args match the argument patterns of
+// c, see [MockCommand.ArgPatterns].
+func (c *MockCommand) matchArgPatterns(args []string) bool {
+	if len(args) != len(c.ArgPatterns) {
+		return false
+	}
+
+	for i, pattern := range c.ArgPatterns {
+		if !matchGlob(pattern, args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Any can be used in place of an argument in [MockCommand.Args] to
+// match any value in that position while the other arguments still
+// have to match exactly, e.g.:
+//
+//	Args: []string{"clone", cmdexec.Any, "--depth", "1"}
+//
+// Commands with exact Args take precedence over ones using Any.
+const Any = "\x00cmdexec.Any\x00"
+
+// matchAnyArgs reports whether args match the arguments of c, where
+// [Any] matches any argument.
+func (c *MockCommand) matchAnyArgs(args []string) bool {
+	if len(args) != len(c.Args) {
+		return false
+	}
+
+	for i, arg := range c.Args {
+		if arg != Any && arg != args[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// matchUnorderedArgs reports whether args match the arguments of c in
+// any order, see [MockCommand.UnorderedArgs].
+func (c *MockCommand) matchUnorderedArgs(args []string) bool {
+	if len(args) != len(c.Args) {
+		return false
+	}
+
+	want, got := slices.Clone(c.Args), slices.Clone(args)
+	slices.Sort(want)
+	slices.Sort(got)
+	return slices.Equal(want, got)
+}
+
+// matchGlob reports whether s matches pattern, where '*' matches any
+// sequence of characters (including none) and all other characters
+// only match themselves. Unlike [path.Match], '*' also matches path
+// separators.
+func matchGlob(pattern, s string) bool {
+	// The position to backtrack to if the rest of s fails to match
+	// after the last '*' seen.
+	starP, starS := -1, -1
+
+	p, i := 0, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			starP, starS = p, i
+			p++
+		case p < len(pattern) && pattern[p] == s[i]:
+			p++
+			i++
+		case starP != -1:
+			// Let the last '*' consume one more character.
+			starS++
+			p, i = starP+1, starS
+		default:
+			return false
+		}
+	}
+
+	// Trailing '*'s match the empty string.
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
@@ -19,20 +19,104 @@ package cmdexec
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
+// mockPIDBase is the lowest synthetic process ID handed out to mocked
+// commands. It is above the maximum process ID allowed by Linux (and
+// other common platforms) to ensure that synthetic process IDs never
+// refer to a real process.
+const mockPIDBase = 1 << 22
+
+// mockPID is the number of synthetic process IDs handed out to started
+// [MockCommand]s that did not set [MockCommand.Pid].
+var mockPID atomic.Int64
+
+// mockRunSeq is the number of times a [MockCommand] has been started,
+// used to determine the order commands ran in, see
+// [MockCommand.After].
+var mockRunSeq atomic.Uint64
+
 // MockExecutor provides an executor that returns mock data.
 type MockExecutor struct {
+	// mu protects the fields below, allowing commands to be registered
+	// while others are being executed.
+	mu sync.RWMutex
+
 	// cmd contains the commands that the executor should mock.
 	cmds map[string]*MockCommand
+
+	// matchers contains the commands that are matched by something other
+	// than their exact name and arguments, in the order they were added.
+	// They are only used if no command in cmds matched.
+	matchers []mockMatcher
+
+	// requireAllUsed denotes if every registered command must have been
+	// run by the time the test has finished, see RequireAllUsed.
+	requireAllUsed bool
+
+	// passthrough denotes if commands that weren't registered should be
+	// executed for real, see Passthrough.
+	passthrough bool
+
+	// defaultCmd is used for commands that weren't registered, see
+	// SetDefault.
+	defaultCmd *MockCommand
+
+	// nameDefaults are used for commands with a given name that weren't
+	// registered, see SetDefaultFor.
+	nameDefaults map[string]*MockCommand
+
+	// scenarios contains the scenarios created through Scenario. Their
+	// steps are used if no command in cmds matched.
+	scenarios []*Scenario
+
+	// caseInsensitiveNames denotes if command names are matched
+	// regardless of their case and extension, see CaseInsensitiveNames.
+	caseInsensitiveNames bool
+
+	// errorOnUnregistered denotes if commands that weren't registered
+	// should fail to start instead of panicking, see
+	// ErrorOnUnregistered.
+	errorOnUnregistered bool
+
+	// callsMu protects calls, which contains every execution of the
+	// registered commands, see Calls, and attempts, which contains the
+	// command line of every command that was started, even if it failed
+	// to, see AssertNoCommands.
+	callsMu  sync.Mutex
+	calls    []Invocation
+	attempts [][]string
+
+	// onMatch are called for every execution of the registered
+	// commands, see OnMatch.
+	onMatch []func(Invocation)
 }
 
 // MockCommand is a command that can be executed by the MockExecutor.
+//
+// Every command created through the executor (e.g., by [Command]) is a
+// new MockCommand for that invocation, holding its own configuration
+// (e.g., set through SetDir) and state, so that registered commands can
+// be run any number of times, including concurrently. The Captured
+// methods of a registered command report on the command most recently
+// created from it, while expectations (e.g., [MockCommand.Times]) count
+// every invocation.
 type MockCommand struct {
 	// Name is the name (or path) of the command that should be called to
 	// trigger this mock.
@@ -42,6 +126,27 @@ type MockCommand struct {
 	// with to trigger this mock.
 	Args []string
 
+	// ArgPatterns, if set, are matched against the arguments the command
+	// is called with instead of Args. A pattern may contain '*'
+	// wildcards, which match any sequence of characters, e.g.,
+	// []string{"-C", "/tmp/*", "status"}. The command must be called with
+	// exactly as many arguments as there are patterns. Commands with
+	// exact Args take precedence over ones with patterns.
+	ArgPatterns []string
+
+	// AnyArgs, if set, matches the command regardless of the arguments
+	// it is called with, ignoring Args and ArgPatterns. Commands with
+	// exact Args take precedence over ones with AnyArgs.
+	AnyArgs bool
+
+	// UnorderedArgs, if set, matches the command when it is called with
+	// the same arguments as Args in any order, so that reordering flags
+	// doesn't break the mock. Arguments are compared as a whole, so
+	// "--key=value" keeps its key and value together, but the value of
+	// "--key value" may be matched anywhere. Commands with exact Args
+	// take precedence over ones with UnorderedArgs.
+	UnorderedArgs bool
+
 	// Stdout is the expected output that the command should write to
 	// stdout.
 	Stdout []byte
@@ -50,48 +155,618 @@ type MockCommand struct {
 	// stderr.
 	Stderr []byte
 
+	// Chunks, if set, is the output of the command as an ordered
+	// sequence of writes to stdout and stderr, replacing Stdout and
+	// Stderr. This allows mocking commands whose stdout and stderr are
+	// interleaved, which is reflected by CombinedOutput and the streams
+	// of the command. Otherwise, all of Stdout is written before Stderr.
+	Chunks []OutputChunk
+
+	// Combined, if set, is returned by CombinedOutput as is, instead of
+	// Stdout and Stderr (or Chunks) written one after another. This
+	// allows using the exact interleaving of a real command's combined
+	// output, e.g., one captured from a log. Output and the streams of
+	// the command are unaffected.
+	Combined []byte
+
+	// FailAfter, if set, makes the command fail after writing only the
+	// first FailAfter bytes of its output (in the order it is written,
+	// see Chunks), dropping the rest. This simulates a command failing
+	// mid-stream, e.g., because the pipe it writes to was closed. Unless
+	// Err or ExitStatus are set, the command exits with status 141, as
+	// reported by shells for a process killed by SIGPIPE.
+	FailAfter int
+
 	// Stdin is the expected input that the command should read from
 	// stdin. If this is set, the command will check that the provided
 	// stdin matches the expected input. SetStdin() must be called to set
 	// the actual stdin data.
 	Stdin []byte
 
+	// StdinMatcher, if set, is called with the input the command read
+	// from stdin, failing the command if it returns an error. This
+	// allows checking stdin that isn't deterministic (e.g., contains
+	// timestamps) instead of comparing it byte-for-byte through Stdin.
+	StdinMatcher func(stdin []byte) error
+
+	// StdinJSON, if set, compares the input the command read from stdin
+	// to Stdin as parsed JSON rather than byte-for-byte, ignoring the
+	// order of object keys, whitespace, and how numbers are formatted.
+	// The error returned on mismatch lists every value that differs.
+	StdinJSON bool
+
+	// StdinContains, if set, are fragments that must all appear in the
+	// input the command read from stdin, otherwise the command fails.
+	StdinContains [][]byte
+
+	// ExpectedEnv, if set, contains environment variables in the form
+	// "key=value" that the environment of the command (see
+	// [MockCommand.Environ]) must contain, otherwise running the command
+	// fails. Other environment variables are allowed.
+	ExpectedEnv []string
+
+	// ExpectedDir, if set, is the working directory that must be set
+	// through SetDir, otherwise running the command fails.
+	ExpectedDir string
+
+	// ExpectedDirPattern, if set, is a pattern the working directory set
+	// through SetDir must match, otherwise running the command fails.
+	// Like ArgPatterns, '*' matches any sequence of characters, e.g.,
+	// "/tmp/TestBuild*/001" for a directory created by [testing.T.TempDir].
+	ExpectedDirPattern string
+
+	// ExpectedDirRegexp, if set, is a regular expression the working
+	// directory set through SetDir must match, otherwise running the
+	// command fails.
+	ExpectedDirRegexp *regexp.Regexp
+
 	// Err is an error that will be returned when the command is executed.
 	// If not set, the command will return nil.
 	Err error
 
+	// ExitStatus is the exit code that the command should exit with. If
+	// non-zero, the command will return an [ExitError] containing Stderr
+	// and wrapping Err, if set. Like [exec.ExitError], the returned error
+	// reports the exit code through its ExitCode method, so code that
+	// checks for an interface{ ExitCode() int } works with both.
+	ExitStatus int
+
+	// Responses, if set, are used in order for each execution of the
+	// command instead of Stdout, Stderr, Err, and ExitStatus. Once all
+	// responses have been used, the command falls back to those fields.
+	// This allows scripting commands that, for example, fail twice
+	// before succeeding.
+	Responses []MockResponse
+
+	// Script, if set, is an interactive conversation the command has
+	// through its stdin and stdout, e.g., to answer a prompt. Each step
+	// is run in order before Stdout and Stderr are written. Stdin isn't
+	// checked against Stdin and is not recorded (see
+	// [MockExecutor.Calls]) for commands with a Script.
+	Script []ScriptStep
+
+	// Handler, if set, computes the output of the command each time it
+	// runs from how it was called, replacing Stdout, Stderr, Chunks, Err,
+	// ExitStatus, and Responses. Stdin is read before Handler is called.
+	// To simulate an exit code, Handler may return an [ExitError].
+	Handler func(inv Invocation) (stdout, stderr []byte, err error)
+
+	// Delay is how long the command takes to run once started. If the
+	// context the command was created with is done first, or the timeout
+	// set through SetTimeout elapses, the command is canceled without
+	// writing any output and Wait returns the context (or timeout) error
+	// (see [MockCommand.SimulateCancel]).
+	Delay time.Duration
+
+	// DelayFunc, if set, is called each time the command runs to
+	// determine its Delay, e.g., to simulate a command that gets slower
+	// on every retry.
+	DelayFunc func(inv Invocation) time.Duration
+
+	// Blocking, if set, makes the command run until
+	// [MockCommand.Complete] is called (after Delay, if set), allowing a
+	// test to control when a long-running command finishes. If the
+	// context the command was created with is done first, or it times
+	// out, it is canceled like with Delay.
+	Blocking bool
+
+	// OutputRate, if set, is the maximum rate in bytes per second at
+	// which the command writes its output (Stdout, Stderr, or Chunks),
+	// allowing stall detection and read deadlines to be tested. Output
+	// is written a tenth of a second worth of bytes at a time. If the
+	// context the command was created with is done while writing, or it
+	// times out, it is canceled like with Delay.
+	OutputRate int
+
+	// OnSignal, if set, is how the command reacts to signals sent to it
+	// while it is running (e.g., during Delay or while Blocking), keyed
+	// by signal. Once a reaction's Delay has elapsed, the command exits
+	// without writing any output it hasn't yet written, e.g.:
+	//
+	//	OnSignal: map[os.Signal]cmdexec.SignalResponse{
+	//		syscall.SIGTERM: {Delay: time.Second, ExitStatus: 143},
+	//		os.Kill:         {},
+	//	}
+	//
+	// Signals without a reaction are only recorded, see
+	// [MockCommand.CapturedSignals]. Like SIGKILL, [os.Kill] (e.g., sent
+	// through [MockCommand.Kill]) can't be handled and always terminates
+	// the command immediately.
+	OnSignal map[os.Signal]SignalResponse
+
+	// ResourceUsage is the resource usage reported by
+	// [MockCommand.Usage] once the command has exited. It is also used
+	// for the CPU times of [MockCommand.ProcessState].
+	ResourceUsage Usage
+
+	// NotFound, if set, makes the command behave like an executable that
+	// doesn't exist: starting it fails with an [*exec.Error] wrapping
+	// [exec.ErrNotFound], like the standard executor does. See
+	// [MockExecutor.AddNotFound].
+	NotFound bool
+
+	// PermissionDenied, if set, makes starting the command fail with an
+	// [*fs.PathError] wrapping [syscall.EACCES], like the standard
+	// executor does for files that aren't executable. It satisfies
+	// errors.Is(err, fs.ErrPermission).
+	PermissionDenied bool
+
+	// MissingDir, if set, makes starting the command fail with an
+	// [*fs.PathError] wrapping [syscall.ENOENT] for the working
+	// directory set through SetDir, like the standard executor does when
+	// it doesn't exist. It satisfies errors.Is(err, fs.ErrNotExist).
+	MissingDir bool
+
+	// ArgListTooLong, if set, makes starting the command fail with an
+	// [*fs.PathError] wrapping [syscall.E2BIG], like the standard
+	// executor does when the arguments are too large for the system.
+	ArgListTooLong bool
+
+	// Pid is the process ID reported by the command once it has been
+	// started. If not set, a unique synthetic process ID is used.
+	Pid int
+
+	// executor is the executor the command was registered with, if any.
+	executor *MockExecutor
+
+	// startErr, if set, is returned by Start, e.g., for commands that
+	// weren't registered (see MockExecutor.ErrorOnUnregistered).
+	startErr error
+
+	// dir is set by SetDir.
+	dir string
+
 	// stdin is a reader that will be used to read from the command's
 	// stdin if provided.
 	stdin io.Reader
+
+	// stdinBytes contains the stdin set by SetStdinString or
+	// SetStdinBytes. Unlike stdin, it is not consumed by a run.
+	stdinBytes []byte
+
+	// env contains the environment variables set by SetEnviron.
+	env []string
+
+	// sysProcAttr contains the attributes set by SetSysProcAttr.
+	sysProcAttr *syscall.SysProcAttr
+
+	// extraFiles contains the files set by SetExtraFiles.
+	extraFiles []*os.File
+
+	// credential is set by SetCredential.
+	credential *[2]uint32
+
+	// nice is set by SetNice.
+	nice *int
+
+	// osStreams is set by UseOSStreams, denoting if stdin was requested.
+	osStreams *bool
+
+	// rlimits is set by SetRlimits.
+	rlimits []Rlimit
+
+	// newProcessGroup is set by SetNewProcessGroup.
+	newProcessGroup bool
+
+	// ptySize is the size of the PTY requested through UsePTY, if
+	// called.
+	ptySize *[2]uint16
+
+	// timeout is set by SetTimeout.
+	timeout time.Duration
+
+	// onStdoutLine and onStderrLine are set by OnStdoutLine and
+	// OnStderrLine respectively.
+	onStdoutLine func(string)
+	onStderrLine func(string)
+
+	// stdoutFile and stderrFile are set by SetStdoutFile and
+	// SetStderrFile respectively. Once started, the opened files are
+	// stored in openStdout and openStderr.
+	stdoutFile *outputFile
+	stderrFile *outputFile
+	openStdout *os.File
+	openStderr *os.File
+
+	// maxOutputBytes is set by SetMaxOutputBytes.
+	maxOutputBytes int64
+
+	// stdout and stderr are set by SetStdout and SetStderr
+	// respectively.
+	stdout io.Writer
+	stderr io.Writer
+
+	// teeStdout and teeStderr are set by TeeStdout and TeeStderr
+	// respectively.
+	teeStdout io.Writer
+	teeStderr io.Writer
+
+	// cancel and waitDelay are set by SetCancel and SetWaitDelay
+	// respectively. cancelSignal is set by SetCancelSignal instead of
+	// cancel, so that clones signal themselves rather than c.
+	cancel       func() error
+	cancelSignal os.Signal
+	waitDelay    time.Duration
+
+	// parent is the registered command this command was created from by
+	// the executor for a single invocation, see invoke. Expectations and
+	// run counts are tracked on the parent.
+	parent *MockCommand
+
+	// statsMu protects the fields below, which are shared by every
+	// invocation of a registered command. They are only used on the
+	// root of a command, see root.
+	statsMu sync.Mutex
+
+	// atLeast and atMost are the expected number of runs set by
+	// AtLeast and AtMost (or Times), if set.
+	atLeast *int
+	atMost  *int
+
+	// uses is the number of times the command may be run, set by Uses.
+	// exhausted is the number of times it was started after that.
+	uses      *int
+	exhausted int
+
+	// runs is the number of times the command has been started.
+	runs int
+
+	// firstRun and lastRun are the sequence numbers (see mockRunSeq) of
+	// the first and last time the command was started, or zero if it
+	// never was.
+	firstRun, lastRun uint64
+
+	// after are the commands that must have run before this one, see
+	// After and InOrder.
+	after []orderConstraint
+
+	// onExit, if set, is called with the result of every invocation of
+	// the command once it has exited, see Scenario.
+	onExit func(error)
+
+	// environCalls contains a copy of the environment passed to every
+	// call of SetEnviron, see CapturedEnvironCalls.
+	environCalls [][]string
+
+	// latest is the command most recently created from this one by the
+	// executor, which the Captured methods report on, see current.
+	latest *MockCommand
+
+	// mu protects the fields below, which track the lifecycle of the
+	// command while it is "running".
+	mu sync.Mutex
+
+	// argv is the name and arguments the command was called with, if it
+	// was created by the executor.
+	argv []string
+
+	// done is closed once the currently started invocation of the
+	// command has finished. It is nil when the command is not running.
+	// lastDone is the same channel, but is kept once the command has been
+	// waited for.
+	done     chan struct{}
+	lastDone chan struct{}
+
+	// waitErr is the error produced by the last invocation of the
+	// command, returned by Wait.
+	waitErr error
+
+	// resp is the response used by the current (or last) invocation of
+	// the command.
+	resp MockResponse
+
+	// pid is the process ID of the last invocation of the command, or
+	// zero if the command has never been started.
+	pid int
+
+	// state is the synthetic state of the last invocation of the command
+	// once it has finished.
+	state *ProcessState
+
+	// signals contains the signals sent to the last invocation of the
+	// command.
+	signals []os.Signal
+
+	// last is the last invocation of the command.
+	last Invocation
+
+	// complete receives the error passed to Complete for the current
+	// invocation of the command, if Blocking is set. completed denotes if
+	// Complete was called.
+	complete  chan error
+	completed bool
+
+	// interrupt ends the current invocation of the command early, with
+	// a cause describing the signal it reacted to, see OnSignal.
+	interrupt context.CancelCauseFunc
+
+	// ctx is the context the command was created with, which cancels it
+	// once done.
+	ctx context.Context
+
+	// canceled is the context error if the current invocation of the
+	// command was canceled (see SimulateCancel), in which case cancelErr
+	// contains the error returned by the cancel function.
+	canceled  error
+	cancelErr error
+
+	// stopped denotes if the current invocation of the command was
+	// canceled or exited because of a signal before it finished
+	// running.
+	stopped bool
+
+	// stdoutPipe, stderrPipe, and stdinPipe are the pipes created by
+	// StdoutPipe, StderrPipe, and StdinPipe respectively for the next
+	// (or current) invocation of the command.
+	stdoutPipe *bufferedPipe
+	stderrPipe *bufferedPipe
+	stdinPipe  *bufferedPipe
+
+	// pty is the PTY of the current (or last) invocation of the command
+	// if UsePTY was called.
+	pty *mockPTY
+}
+
+// MockResponse is the result of a single execution of a
+// [MockCommand], see [MockCommand.Responses]. The fields match those of
+// [MockCommand]. If Stdin or ExpectedEnv are nil, those of the command
+// are used.
+type MockResponse struct {
+	Stdin       []byte
+	ExpectedEnv []string
+
+	Stdout     []byte
+	Stderr     []byte
+	Chunks     []OutputChunk
+	Combined   []byte
+	FailAfter  int
+	Err        error
+	ExitStatus int
+}
+
+// chunks returns the output of the response in the order it should be
+// written. Without Chunks, all of stdout is written before stderr.
+func (r *MockResponse) chunks() []OutputChunk {
+	if len(r.Chunks) > 0 {
+		return r.Chunks
+	}
+	return []OutputChunk{{Stream: StreamStdout, Data: r.Stdout}, {Stream: StreamStderr, Data: r.Stderr}}
+}
+
+// combined returns stdout and stderr of the response interleaved in
+// the order they are written, or Combined if set.
+func (r *MockResponse) combined() []byte {
+	if r.Combined != nil {
+		return r.Combined
+	}
+
+	var b []byte
+	for _, chunk := range r.chunks() {
+		b = append(b, chunk.Data...)
+	}
+	return b
+}
+
+// Stream is an output stream of a command.
+type Stream int
+
+// Contains the output streams of a command.
+const (
+	StreamStdout Stream = iota
+	StreamStderr
+)
+
+// OutputChunk is a chunk of output written to a single stream of a
+// command, see [MockCommand.Chunks].
+type OutputChunk struct {
+	Stream Stream
+	Data   []byte
+
+	// After is how long to wait before writing the chunk, allowing
+	// output to be written progressively (e.g., progress updates). If
+	// the context the command was created with is done while waiting,
+	// the command is canceled (see [MockCommand.Delay]).
+	After time.Duration
+}
+
+// nextResponse returns the response to use for the run-th execution of
+// the command, starting at zero.
+func (c *MockCommand) nextResponse(run int) MockResponse {
+	if run < len(c.Responses) {
+		resp := c.Responses[run]
+		if resp.Stdin == nil {
+			resp.Stdin = c.Stdin
+		}
+		if resp.ExpectedEnv == nil {
+			resp.ExpectedEnv = c.ExpectedEnv
+		}
+		return resp
+	}
+
+	return MockResponse{
+		Stdin:       c.Stdin,
+		ExpectedEnv: c.ExpectedEnv,
+		Stdout:      c.Stdout,
+		Stderr:      c.Stderr,
+		Chunks:      c.Chunks,
+		Combined:    c.Combined,
+		FailAfter:   c.FailAfter,
+		Err:         c.Err,
+		ExitStatus:  c.ExitStatus,
+	}
+}
+
+// sigpipeExitStatus is the exit status of a command that failed after
+// writing to a closed pipe, see [MockCommand.FailAfter].
+const sigpipeExitStatus = 128 + 13
+
+// failAfter truncates the output of r to its first FailAfter bytes and
+// makes it fail, if FailAfter is set. The result must be passed to
+// splitChunks.
+func failAfter(r MockResponse) MockResponse {
+	if r.FailAfter <= 0 {
+		return r
+	}
+
+	n := r.FailAfter
+	var chunks []OutputChunk
+	for _, chunk := range r.chunks() {
+		if n == 0 {
+			break
+		}
+
+		chunk.Data = chunk.Data[:min(n, len(chunk.Data))]
+		n -= len(chunk.Data)
+		chunks = append(chunks, chunk)
+	}
+	r.Chunks = chunks
+	if r.Combined != nil {
+		r.Combined = r.Combined[:min(r.FailAfter, len(r.Combined))]
+	}
+
+	if r.Err == nil && r.ExitStatus == 0 {
+		r.ExitStatus = sigpipeExitStatus
+	}
+	return r
+}
+
+// splitChunks sets Stdout and Stderr of r from its Chunks, if set.
+func splitChunks(r MockResponse) MockResponse {
+	if len(r.Chunks) == 0 {
+		return r
+	}
+
+	r.Stdout, r.Stderr = nil, nil
+	for _, chunk := range r.Chunks {
+		if chunk.Stream == StreamStderr {
+			r.Stderr = append(r.Stderr, chunk.Data...)
+		} else {
+			r.Stdout = append(r.Stdout, chunk.Data...)
+		}
+	}
+	return r
+}
+
+// mockPTY emulates the controlling side of a PTY for a [MockCommand].
+// Reads return the output of the command, while writes are recorded.
+type mockPTY struct {
+	out *bufferedPipe
+
+	mu   sync.Mutex
+	in   bytes.Buffer
+	size [2]uint16
+}
+
+// Read implements [io.Reader].
+func (p *mockPTY) Read(b []byte) (int, error) {
+	return p.out.Read(b)
+}
+
+// Write implements [io.Writer].
+func (p *mockPTY) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.in.Write(b)
+}
+
+// readStdin returns the stdin provided to the command, reading it
+// until EOF if a reader was provided. It returns nil if no stdin was
+// provided.
+func (c *MockCommand) readStdin() ([]byte, error) {
+	if c.stdin == nil {
+		return c.stdinBytes, nil
+	}
+
+	b, err := io.ReadAll(c.stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stdin: %w", err)
+	}
+	return b, nil
 }
 
 // checkStdin checks if the provided stdin matches the expected input.
 // This is only checked if both SetStdin() was called on a given command
-// and that we expected stdin to be provided.
-func (c *MockCommand) checkStdin() error {
-	if len(c.Stdin) == 0 {
+// and that we expected stdin to be provided, either through Stdin,
+// StdinContains, or StdinMatcher.
+func (c *MockCommand) checkStdin(got []byte) error {
+	if len(c.resp.Stdin) == 0 && len(c.StdinContains) == 0 && c.StdinMatcher == nil {
 		return nil
 	}
 
-	if c.stdin == nil {
+	if got == nil {
 		return fmt.Errorf("expected stdin to be provided but it was not (was SetStdin() called?)")
 	}
 
-	got := make([]byte, len(c.Stdin))
-	if _, err := c.stdin.Read(got); err != nil {
-		return err
+	switch {
+	case len(c.resp.Stdin) == 0:
+	case c.StdinJSON:
+		if err := checkJSON(c.resp.Stdin, got); err != nil {
+			return err
+		}
+	case !bytes.Equal(got, c.resp.Stdin):
+		return errors.New(mismatch("expected stdin set by SetStdin()", c.resp.Stdin, got))
 	}
 
-	if !bytes.Equal(got, c.Stdin) {
-		// Read the rest of the input to provide a more friendly error
-		// message.
-		rest, err := io.ReadAll(c.stdin)
-		if err != nil {
-			return fmt.Errorf("failed to read remaining stdin: %w", err)
+	for _, fragment := range c.StdinContains {
+		if !bytes.Contains(got, fragment) {
+			return fmt.Errorf("expected stdin set by SetStdin() to contain %q but got %q", fragment, got)
+		}
+	}
+
+	if c.StdinMatcher != nil {
+		if err := c.StdinMatcher(got); err != nil {
+			return fmt.Errorf("stdin set by SetStdin() didn't match: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// checkEnv checks if env contains the expected environment variables
+// set through [MockCommand.ExpectedEnv].
+func (c *MockCommand) checkEnv(env []string) error {
+	for _, kv := range c.resp.ExpectedEnv {
+		if !slices.Contains(env, kv) {
+			return fmt.Errorf("expected environment to contain %q but it did not (was SetEnviron() called?)", kv)
 		}
-		got = append(got, rest...)
+	}
+
+	return nil
+}
 
-		return fmt.Errorf("expected stdin set by SetStdin() to be %q but got %q", string(c.Stdin), got)
+// checkDir checks if dir matches the working directory expected
+// through [MockCommand.ExpectedDir], [MockCommand.ExpectedDirPattern],
+// and [MockCommand.ExpectedDirRegexp].
+func (c *MockCommand) checkDir(dir string) error {
+	if c.ExpectedDir != "" && filepath.Clean(dir) != filepath.Clean(c.ExpectedDir) {
+		return fmt.Errorf("expected working directory set by SetDir() to be %q but got %q", c.ExpectedDir, dir)
+	}
+	if c.ExpectedDirPattern != "" && !matchGlob(c.ExpectedDirPattern, filepath.Clean(dir)) {
+		return fmt.Errorf("expected working directory set by SetDir() to match pattern %q but got %q", c.ExpectedDirPattern, dir)
+	}
+	if c.ExpectedDirRegexp != nil && !c.ExpectedDirRegexp.MatchString(dir) {
+		return fmt.Errorf("expected working directory set by SetDir() to match regexp %q but got %q", c.ExpectedDirRegexp, dir)
 	}
 
 	return nil
@@ -100,113 +775,1667 @@ func (c *MockCommand) checkStdin() error {
 // Output implements the [Cmd] interface, see [Cmd.Output] for more
 // information.
 func (c *MockCommand) Output() ([]byte, error) {
-	return c.Stdout, c.Run()
+	err := c.Run()
+	if c.ptySize != nil {
+		// Both stdout and stderr are written to the terminal.
+		return c.limitOutput(toCRLF(c.resp.combined()), err)
+	}
+	return c.limitOutput(c.resp.Stdout, err)
 }
 
 // CombinedOutput implements the [Cmd] interface, see
 // [Cmd.CombinedOutput] for more information.
 func (c *MockCommand) CombinedOutput() ([]byte, error) {
-	return append(c.Stdout, c.Stderr...), c.Run()
+	err := c.Run()
+	return c.limitOutput(c.resp.combined(), err)
+}
+
+// RunReport implements the [Cmd] interface, see [Cmd.RunReport] for
+// more information. For the MockCommand, the result is synthesized from
+// the mocked output.
+func (c *MockCommand) RunReport() (*RunResult, error) {
+	start := time.Now()
+	err := c.Run()
+	return newRunResult(c, start, c.resp.Stdout, c.resp.Stderr), err
 }
 
 // Run implements the [Cmd] interface, see [Cmd.Run] for more
 // information.
 func (c *MockCommand) Run() error {
-	if err := c.checkStdin(); err != nil {
+	if err := c.Start(); err != nil {
 		return err
 	}
 
-	return c.Err
+	return c.Wait()
 }
 
-// String implements the [Cmd] interface, see [Cmd.String] for more
-// information.
-func (c *MockCommand) String() string {
-	// If possible to look up the command in the PATH, we should return
-	// the full path to the command. This is mostly to match the behavior
-	// of [exec.Cmd.String].
-	execPath := c.Name
-	if realPath, err := exec.LookPath(c.Name); err == nil {
-		execPath = realPath
+// Start implements the [Cmd] interface, see [Cmd.Start] for more
+// information. The mocked "process" runs in the background until it
+// has finished, at which point [MockCommand.Wait] returns its result.
+//
+// Unlike [exec.Cmd], a MockCommand may be started again once Wait has
+// returned.
+func (c *MockCommand) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done != nil {
+		return errors.New("exec: already started")
+	}
+	if c.executor != nil {
+		c.executor.attempt(c.argvLocked())
+	}
+	if c.startErr != nil {
+		return c.startErr
+	}
+	if err := c.startFailure(); err != nil {
+		return err
 	}
 
-	return strings.Join(append([]string{execPath}, c.Args...), " ")
-}
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		// Like [exec.Cmd.Start], don't start the command if the context
+		// is already done.
+		return err
+	}
 
-// SetEnviron implements the [Cmd] interface. For the MockCommand, this
-// is a no-op because we do not actually execute any commands.
-func (c *MockCommand) SetEnviron(_ []string) {}
+	var err error
+	c.openStdout, c.openStderr, err = openOutputFiles(c.stdoutFile, c.stderrFile)
+	if err != nil {
+		return err
+	}
 
-// SetDir implements the [Cmd] interface. For the MockCommand, this is a
-// no-op because we do not actually execute any commands.
-func (c *MockCommand) SetDir(_ string) {}
+	run, onExit, err := c.root().beginRun(c.argvLocked())
+	if err != nil {
+		closeOutputFiles(c.openStdout, c.openStderr)
+		c.openStdout, c.openStderr = nil, nil
+		return err
+	}
 
-// SetStdout implements the [Cmd] interface. For the MockCommand, this
-// is a no-op because we do not actually execute any commands.
-func (c *MockCommand) SetStdout(_ io.Writer) {}
+	done := make(chan struct{})
+	c.done, c.lastDone = done, done
+	c.resp = splitChunks(failAfter(c.nextResponse(run)))
+	c.state = nil
+	c.signals = nil
+	c.canceled, c.cancelErr = nil, nil
+	c.stopped = false
+	c.pty = nil
+	if c.ptySize != nil {
+		c.pty = &mockPTY{out: newBufferedPipe(), size: *c.ptySize}
+	}
+	c.complete, c.completed = nil, false
+	if c.Blocking {
+		c.complete = make(chan error, 1)
+	}
+	c.pid = c.Pid
+	if c.pid == 0 {
+		c.pid = mockPIDBase + int(mockPID.Add(1))
+	}
 
-// SetStderr implements the [Cmd] interface. For the MockCommand, this
-// is a no-op because we do not actually execute any commands.
-func (c *MockCommand) SetStderr(_ io.Writer) {}
+	pid := c.pid
+	argv := c.argvLocked()
+	inv := Invocation{
+		Name: argv[0],
+		Args: argv[1:],
+		Env:  c.Environ(),
+		Dir:  c.dir,
+		TTY:  c.ptySize != nil,
+		Time: time.Now(),
+	}
+	c.last = inv
+	stopTimeout := context.CancelFunc(func() {})
+	if c.timeout > 0 {
+		ctx, stopTimeout = context.WithTimeoutCause(ctx, c.timeout, newTimeoutError(c.timeout))
+	}
+	ctx, c.interrupt = context.WithCancelCause(ctx)
+	interrupt := c.interrupt
+	track(c)
+	go func() {
+		defer close(done)
+		defer untrack(c)
+		defer stopTimeout()
+		defer interrupt(nil)
 
-// SetStdin sets the stdin of the command to the given reader. This is
-// used for validation purposes to ensure that the provided stdin
-// matches what was expected.
-func (c *MockCommand) SetStdin(r io.Reader) {
-	c.stdin = r
+		err := c.run(ctx, inv)
+		if onExit != nil {
+			onExit(err)
+		}
+
+		c.mu.Lock()
+		canceled := c.canceled != nil
+		c.mu.Unlock()
+
+		// Errors not caused by the process exiting (e.g., failing to
+		// start or being killed) have no exit code.
+		code := 0
+		if err != nil || canceled {
+			code = -1
+
+			var exitErr *ExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.Code
+			}
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.waitErr = err
+		c.state = &ProcessState{
+			Pid:        pid,
+			ExitCode:   code,
+			Exited:     code != -1,
+			Success:    code == 0,
+			SystemTime: c.ResourceUsage.SystemTime,
+			UserTime:   c.ResourceUsage.UserTime,
+		}
+	}()
+
+	return nil
 }
 
-// UseOSStreams implements the [Cmd] interface. For the MockCommand,
-// this is a no-op because we do not actually execute any commands.
-func (c *MockCommand) UseOSStreams(_ bool) {}
+// beginRun counts a new run of the command, returning its index and the
+// function to call once it has exited. An error wrapping
+// [ErrMockExhausted] is returned if the command may not be run again,
+// see Uses. argv is the command line the command was called with.
+func (c *MockCommand) beginRun(argv []string) (run int, onExit func(error), err error) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
 
-// NewMockExecutor returns a new MockExecutor with the given commands. A
-// [MockExecutor] contains various commands that should be mocked
-// instead of actually executed.
-//
-// Once a [MockExecutor] is created and used with [UseMockExecutor], it
-// will error if any commands are executed that were not registered with
-// the executor.
-//
-// Commands that have had SetStdin() called and set Stdin data will also
-// have their stdin checked to ensure that the provided input matches
-// the expected input, this enables testing of commands that read from
-// stdin.
-func NewMockExecutor(cmds ...*MockCommand) *MockExecutor {
-	me := &MockExecutor{}
-	me.cmds = make(map[string]*MockCommand)
-	for _, cmd := range cmds {
-		me.AddCommand(cmd)
+	if c.uses != nil && c.runs >= *c.uses {
+		c.exhausted++
+		return 0, nil, fmt.Errorf("%w: '%s' may only be run %d times",
+			ErrMockExhausted, strings.Join(argv, " "), *c.uses,
+		)
 	}
-	return me
+
+	run = c.runs
+	c.runs++
+	c.lastRun = mockRunSeq.Add(1)
+	if c.firstRun == 0 {
+		c.firstRun = c.lastRun
+	}
+	return run, c.onExit, nil
 }
 
-// getCommandKey returns a unique key for a command based on its name
-// and arguments.
-func (e *MockExecutor) getCommandKey(name string, args ...string) string {
-	return base64.StdEncoding.EncodeToString([]byte(name + " " + strings.Join(args, " ")))
+// root returns the registered command c was created from, or c itself
+// if it wasn't created by the executor.
+func (c *MockCommand) root() *MockCommand {
+	if c.parent != nil {
+		return c.parent
+	}
+	return c
 }
 
-// AddCommand adds a command to the executor. If the command has
-// already been added, it will be replaced.
-//
-// Note: This is not thread-safe.
-func (e *MockExecutor) AddCommand(cmd *MockCommand) {
-	e.cmds[e.getCommandKey(cmd.Name, cmd.Args...)] = cmd
+// current returns the command most recently created from c by the
+// executor, or c itself if there is none.
+func (c *MockCommand) current() *MockCommand {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	if c.latest != nil {
+		return c.latest
+	}
+	return c
 }
 
-// executor implements the [executorFn] type, returning a Cmd based on
-// the provided arguments. If no commands are available based on the
-// provided input, this function will panic.
-func (e *MockExecutor) executor(_ context.Context, name string, arg ...string) Cmd {
-	key := e.getCommandKey(name, arg...)
-	if cmd, ok := e.cmds[key]; ok {
-		return cmd
+// invoke returns a new command for a single invocation of c, called
+// with the provided name and arguments and created with ctx.
+func (c *MockCommand) invoke(ctx context.Context, name string, args []string) *MockCommand {
+	root := c.root()
+	inv := &MockCommand{
+		Name:               c.Name,
+		Args:               c.Args,
+		ArgPatterns:        c.ArgPatterns,
+		AnyArgs:            c.AnyArgs,
+		UnorderedArgs:      c.UnorderedArgs,
+		Stdout:             c.Stdout,
+		Stderr:             c.Stderr,
+		Chunks:             c.Chunks,
+		Combined:           c.Combined,
+		FailAfter:          c.FailAfter,
+		Stdin:              c.Stdin,
+		StdinMatcher:       c.StdinMatcher,
+		StdinJSON:          c.StdinJSON,
+		StdinContains:      c.StdinContains,
+		ExpectedEnv:        c.ExpectedEnv,
+		ExpectedDir:        c.ExpectedDir,
+		ExpectedDirPattern: c.ExpectedDirPattern,
+		ExpectedDirRegexp:  c.ExpectedDirRegexp,
+		Err:                c.Err,
+		ExitStatus:         c.ExitStatus,
+		Responses:          c.Responses,
+		Script:             c.Script,
+		Handler:            c.Handler,
+		Delay:              c.Delay,
+		DelayFunc:          c.DelayFunc,
+		Blocking:           c.Blocking,
+		OutputRate:         c.OutputRate,
+		OnSignal:           c.OnSignal,
+		ResourceUsage:      c.ResourceUsage,
+		NotFound:           c.NotFound,
+		PermissionDenied:   c.PermissionDenied,
+		MissingDir:         c.MissingDir,
+		ArgListTooLong:     c.ArgListTooLong,
+		Pid:                c.Pid,
+
+		parent:   root,
+		executor: c.executor,
+		startErr: c.startErr,
+		argv:     append([]string{name}, args...),
+		ctx:      ctx,
 	}
 
-	panic(
-		fmt.Errorf("cmdexec: no command registered for '%s %s' "+
-			"missing call to MockExecutor.AddCommand?", name, strings.Join(arg, " "),
-		),
-	)
+	root.statsMu.Lock()
+	defer root.statsMu.Unlock()
+	root.latest = inv
+	return inv
+}
+
+// startFailure returns the error starting the command should fail
+// with, e.g., because of NotFound. This must be called with c.mu held.
+func (c *MockCommand) startFailure() error {
+	name := c.argvLocked()[0]
+	switch {
+	case c.NotFound:
+		return &exec.Error{Name: name, Err: exec.ErrNotFound}
+	case c.MissingDir:
+		return &fs.PathError{Op: "chdir", Path: c.dir, Err: syscall.ENOENT}
+	case c.PermissionDenied:
+		return &fs.PathError{Op: "fork/exec", Path: lookPath(name), Err: syscall.EACCES}
+	case c.ArgListTooLong:
+		return &fs.PathError{Op: "fork/exec", Path: lookPath(name), Err: errArgListTooLong}
+	}
+	return nil
+}
+
+// run simulates the execution of the command, returning the error that
+// the command should exit with.
+func (c *MockCommand) run(ctx context.Context, inv Invocation) error {
+	var stdin []byte
+	var err error
+	if len(c.Script) == 0 {
+		// Commands with a Script read stdin while it runs.
+		stdin, err = c.readStdin()
+	}
+	inv.Stdin = stdin
+
+	c.mu.Lock()
+	c.last.Stdin = stdin
+	c.mu.Unlock()
+	if c.executor != nil {
+		c.executor.record(inv)
+	}
+
+	if err == nil {
+		err = c.checkStdin(stdin)
+	}
+	if err == nil {
+		err = c.checkEnv(inv.Env)
+	}
+	if err == nil {
+		err = c.checkDir(inv.Dir)
+	}
+	if err == nil && c.Handler != nil {
+		stdout, stderr, herr := c.Handler(inv)
+
+		// Fill in what the handler can't know about, like the standard
+		// executor does for [exec.ExitError].
+		var exitErr *ExitError
+		if errors.As(herr, &exitErr) {
+			if exitErr.Command == "" {
+				exitErr.Command = c.String()
+			}
+			if exitErr.Stderr == nil {
+				exitErr.Stderr = stderr
+			}
+		}
+
+		c.mu.Lock()
+		c.resp = MockResponse{Stdout: stdout, Stderr: stderr, Err: herr}
+		c.mu.Unlock()
+	}
+	delay := c.Delay
+	if c.DelayFunc != nil {
+		delay = c.DelayFunc(inv)
+	}
+	if err == nil && delay > 0 {
+		c.wait(ctx, delay)
+	}
+	if err == nil && c.Blocking {
+		c.block(ctx)
+	}
+
+	if werr := c.writeOutput(ctx); err == nil {
+		err = werr
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.resp.ExitStatus != 0 {
+		return &ExitError{
+			Command: c.String(),
+			Code:    c.resp.ExitStatus,
+			Stderr:  c.resp.Stderr,
+			Err:     c.resp.Err,
+		}
+	}
+	return c.resp.Err
+}
+
+// block blocks until Complete is called, replacing the error of the
+// command if provided, or until ctx is done, see Blocking.
+func (c *MockCommand) block(ctx context.Context) {
+	c.mu.Lock()
+	complete, stopped := c.complete, c.stopped
+	c.mu.Unlock()
+	if stopped {
+		return
+	}
+
+	select {
+	case err := <-complete:
+		if err != nil {
+			c.mu.Lock()
+			c.resp.Err = err
+			c.mu.Unlock()
+		}
+	case <-ctx.Done():
+		c.cancelWith(ctx)
+	}
+}
+
+// cancelWith cancels the command because ctx is done, like
+// [exec.CommandContext] would, discarding its output. If ctx is done
+// because the command reacted to a signal (see OnSignal), it exits
+// with that reaction instead.
+func (c *MockCommand) cancelWith(ctx context.Context) {
+	resp, signaled := c.signaled(ctx)
+	if !signaled {
+		// Timeouts set through SetTimeout are reported with their own
+		// error, which wraps [context.DeadlineExceeded].
+		cause := ctx.Err()
+		if err := context.Cause(ctx); errors.Is(err, context.DeadlineExceeded) {
+			cause = err
+		}
+		c.simulateCancel(cause) //nolint:errcheck // Why: Returned by Wait.
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resp = resp
+	c.stopped = true
+}
+
+// Complete lets the running invocation of a command with Blocking set
+// finish. If err is not nil, it is returned by Wait instead of Err. An
+// error is returned if the command isn't running or Complete was
+// already called for this invocation.
+func (c *MockCommand) Complete(err error) error {
+	c = c.current()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done == nil || c.complete == nil {
+		return errors.New("cmdexec: command is not running or not Blocking")
+	}
+
+	if c.completed {
+		return errors.New("cmdexec: command was already completed")
+	}
+
+	c.completed = true
+	c.complete <- err
+	return nil
+}
+
+// writeOutput writes the output of the command to any pipes created for
+// it, closing them once done. An error is returned if the Script of the
+// command failed.
+func (c *MockCommand) writeOutput(ctx context.Context) error {
+	var stdout, stderr []io.Writer
+	if c.openStdout != nil {
+		stdout = append(stdout, c.openStdout)
+	}
+	if c.openStderr != nil {
+		stderr = append(stderr, c.openStderr)
+	}
+	if c.teeStdout != nil {
+		stdout = append(stdout, c.teeStdout)
+	}
+	if c.teeStderr != nil {
+		stderr = append(stderr, c.teeStderr)
+	}
+
+	var stdoutLines, stderrLines *lineWriter
+	if c.onStdoutLine != nil {
+		stdoutLines = &lineWriter{fn: c.onStdoutLine}
+		stdout = append(stdout, stdoutLines)
+	}
+	if c.onStderrLine != nil {
+		stderrLines = &lineWriter{fn: c.onStderrLine}
+		stderr = append(stderr, stderrLines)
+	}
+
+	if c.pty != nil {
+		// Both stdout and stderr are written to the terminal, which is
+		// read as stdout.
+		stdout = append(stdout, &crlfWriter{w: c.pty.out})
+		stderr = stdout
+	} else {
+		if c.stdout != nil {
+			stdout = append(stdout, c.stdout)
+		}
+		if c.stdoutPipe != nil {
+			stdout = append(stdout, c.stdoutPipe)
+		}
+		if c.stderr != nil {
+			stderr = append(stderr, c.stderr)
+		}
+		if c.stderrPipe != nil {
+			stderr = append(stderr, c.stderrPipe)
+		}
+	}
+
+	// The script adds its output to the response, which must not be
+	// written again.
+	chunks := c.resp.chunks()
+
+	var err error
+	if len(c.Script) > 0 && ctx.Err() == nil {
+		err = c.runScript(stdout)
+	}
+
+write:
+	for _, chunk := range chunks {
+		if err != nil {
+			break
+		}
+
+		if !c.wait(ctx, chunk.After) {
+			break
+		}
+
+		writers := stdout
+		if chunk.Stream == StreamStderr {
+			writers = stderr
+		}
+
+		for data := chunk.Data; len(data) > 0; {
+			n := len(data)
+			if c.OutputRate > 0 {
+				// Write a tenth of a second worth of output at a time.
+				n = min(n, max(c.OutputRate/10, 1))
+				if !c.wait(ctx, time.Duration(n)*time.Second/time.Duration(c.OutputRate)) {
+					break write
+				}
+			}
+
+			for _, w := range writers {
+				w.Write(data[:n]) //nolint:errcheck // Why: Mirrors a process ignoring EPIPE.
+			}
+			data = data[n:]
+		}
+	}
+
+	for _, pipe := range []*bufferedPipe{c.stdoutPipe, c.stderrPipe} {
+		if pipe != nil {
+			pipe.CloseWrite() //nolint:errcheck // Why: Never fails.
+		}
+	}
+	if c.pty != nil {
+		c.pty.out.CloseWrite() //nolint:errcheck // Why: Never fails.
+	}
+	closeOutputFiles(c.openStdout, c.openStderr)
+	for _, lines := range []*lineWriter{stdoutLines, stderrLines} {
+		if lines != nil {
+			lines.Flush()
+		}
+	}
+
+	return err
+}
+
+// wait blocks for d while writing output. If ctx is done first, the
+// command is canceled and false is returned.
+func (c *MockCommand) wait(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		c.cancelWith(ctx)
+		return false
+	}
+}
+
+// Done implements the [Cmd] interface, see [Cmd.Done] for more
+// information.
+func (c *MockCommand) Done() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lastDone
+}
+
+// Wait implements the [Cmd] interface, see [Cmd.Wait] for more
+// information.
+func (c *MockCommand) Wait() error {
+	c.mu.Lock()
+	done := c.done
+	c.mu.Unlock()
+
+	if done == nil {
+		return errors.New("exec: not started")
+	}
+	<-done
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.done = nil
+
+	err := c.waitErr
+	if c.canceled != nil {
+		// Mirror [exec.Cmd.Wait] returning the error from Cancel, or the
+		// context error, if the command was canceled.
+		switch {
+		case c.cancelErr != nil && !errors.Is(c.cancelErr, os.ErrProcessDone):
+			if err == nil {
+				err = fmt.Errorf("exec: canceling Cmd: %w", c.cancelErr)
+			}
+		case err == nil:
+			err = c.canceled
+		default:
+			err = fmt.Errorf("%w: %w", c.canceled, err)
+		}
+	}
+
+	// Mirror [exec.Cmd.Wait] which closes all pipes once the command has
+	// exited.
+	if c.stdinPipe != nil {
+		c.stdinPipe.CloseRead() //nolint:errcheck // Why: Never fails.
+		c.stdin = nil
+	}
+	c.stdoutPipe, c.stderrPipe, c.stdinPipe = nil, nil, nil
+
+	return err
+}
+
+// ExitCode implements the [Cmd] interface, see [Cmd.ExitCode] for more
+// information. Once the command has finished, this returns
+// [MockCommand.ExitStatus], or -1 if the command failed without
+// setting it.
+func (c *MockCommand) ExitCode() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == nil {
+		return -1
+	}
+	return c.state.ExitCode
+}
+
+// PID implements the [Cmd] interface, see [Cmd.PID] for more
+// information.
+func (c *MockCommand) PID() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pid == 0 {
+		return -1
+	}
+	return c.pid
+}
+
+// Usage implements the [Cmd] interface, see [Cmd.Usage] for more
+// information. Once the command has exited, [MockCommand.ResourceUsage]
+// is returned.
+func (c *MockCommand) Usage() *Usage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == nil {
+		return nil
+	}
+	usage := c.ResourceUsage
+	return &usage
+}
+
+// ProcessState implements the [Cmd] interface, see [Cmd.ProcessState]
+// for more information. The returned state is synthesized from the
+// result of the command.
+func (c *MockCommand) ProcessState() *ProcessState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.state
+}
+
+// Signal implements the [Cmd] interface, see [Cmd.Signal] for more
+// information. Signals sent to the command are recorded and can be
+// retrieved with [MockCommand.CapturedSignals].
+func (c *MockCommand) Signal(sig os.Signal) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pid == 0 {
+		return errors.New("exec: not started")
+	}
+
+	// Like [os.Process.Signal], only report the process as done once it
+	// has been waited on.
+	if c.done == nil {
+		return os.ErrProcessDone
+	}
+
+	c.signals = append(c.signals, sig)
+	c.react(sig)
+	return nil
+}
+
+// Kill implements the [Cmd] interface, see [Cmd.Kill] for more
+// information. This is recorded as [os.Kill] being sent to the command,
+// which terminates it immediately regardless of [MockCommand.OnSignal].
+func (c *MockCommand) Kill() error {
+	return c.Signal(os.Kill)
+}
+
+// SetCredential implements the [Cmd] interface. For the MockCommand,
+// the user and group ID are recorded for inspection through
+// [MockCommand.CapturedCredential], but are otherwise not used.
+func (c *MockCommand) SetCredential(uid, gid uint32) {
+	c.credential = &[2]uint32{uid, gid}
+}
+
+// CapturedCredential returns the user and group ID set through
+// SetCredential. ok is false if SetCredential was never called.
+func (c *MockCommand) CapturedCredential() (uid, gid uint32, ok bool) {
+	c = c.current()
+
+	if c.credential == nil {
+		return 0, 0, false
+	}
+	return c.credential[0], c.credential[1], true
+}
+
+// SetNice implements the [Cmd] interface. For the MockCommand, the
+// niceness is recorded for inspection through
+// [MockCommand.CapturedNice], but is otherwise not used.
+func (c *MockCommand) SetNice(level int) {
+	c.nice = &level
+}
+
+// CapturedNice returns the niceness set through SetNice. ok is false if
+// SetNice was never called.
+func (c *MockCommand) CapturedNice() (level int, ok bool) {
+	c = c.current()
+
+	if c.nice == nil {
+		return 0, false
+	}
+	return *c.nice, true
+}
+
+// SetRlimits implements the [Cmd] interface. For the MockCommand, the
+// limits are recorded for inspection through
+// [MockCommand.CapturedRlimits], but are otherwise not used.
+func (c *MockCommand) SetRlimits(limits ...Rlimit) {
+	c.rlimits = limits
+}
+
+// CapturedRlimits returns the resource limits set through SetRlimits.
+func (c *MockCommand) CapturedRlimits() []Rlimit {
+	c = c.current()
+	return c.rlimits
+}
+
+// SetNewProcessGroup implements the [Cmd] interface. For the
+// MockCommand, this is recorded for inspection through
+// [MockCommand.CapturedNewProcessGroup].
+func (c *MockCommand) SetNewProcessGroup(enabled bool) {
+	c.newProcessGroup = enabled
+}
+
+// CapturedNewProcessGroup returns the value provided to
+// [MockCommand.SetNewProcessGroup].
+func (c *MockCommand) CapturedNewProcessGroup() bool {
+	c = c.current()
+	return c.newProcessGroup
+}
+
+// KillGroup implements the [Cmd] interface, see [Cmd.KillGroup] for
+// more information. Like [MockCommand.Kill], this is recorded as
+// [os.Kill] being sent to the command.
+func (c *MockCommand) KillGroup() error {
+	if !c.newProcessGroup && c.ptySize == nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.pid == 0 {
+			return errors.New("exec: not started")
+		}
+		return errNoProcessGroup
+	}
+
+	return c.Kill()
+}
+
+// KillTree implements the [Cmd] interface, see [Cmd.KillTree] for more
+// information. Like [MockCommand.Kill], this is recorded as [os.Kill]
+// being sent to the command.
+func (c *MockCommand) KillTree() error {
+	return c.Kill()
+}
+
+// CapturedStdin returns the stdin read by the last invocation of the
+// command, or nil if none was provided. Stdin provided through a reader
+// (e.g., [MockCommand.SetStdin]) is read until EOF while the command
+// runs.
+func (c *MockCommand) CapturedStdin() []byte {
+	c = c.current()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.last.Stdin
+}
+
+// CapturedDir returns the working directory of the last invocation of
+// the command set through [MockCommand.SetDir], or an empty string if it
+// was never set or the command has never been started.
+func (c *MockCommand) CapturedDir() string {
+	c = c.current()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.last.Dir
+}
+
+// CapturedEnv returns the environment of the last invocation of the
+// command (see [MockCommand.Environ]), or nil if it has never been
+// started.
+func (c *MockCommand) CapturedEnv() []string {
+	c = c.current()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.last.Env
+}
+
+// CapturedEnvironCalls returns the environment passed to every call of
+// [MockCommand.SetEnviron] across all invocations of the command, in
+// the order they were made. This allows asserting on how an environment
+// was built up, while [MockCommand.Environ] (or
+// [MockCommand.CapturedEnv] once started) returns the effective
+// environment, including changes made through
+// [MockCommand.AppendEnv].
+func (c *MockCommand) CapturedEnvironCalls() [][]string {
+	c = c.root()
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	calls := make([][]string, len(c.environCalls))
+	for i, env := range c.environCalls {
+		calls[i] = slices.Clone(env)
+	}
+	return calls
+}
+
+// CapturedSignals returns the signals sent to the last invocation of
+// the command through [MockCommand.Signal] and [MockCommand.Kill], in
+// the order they were sent.
+func (c *MockCommand) CapturedSignals() []os.Signal {
+	c = c.current()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]os.Signal(nil), c.signals...)
+}
+
+// StdoutPipe implements the [Cmd] interface, see [Cmd.StdoutPipe] for
+// more information. The returned reader contains the data from
+// [MockCommand.Stdout] once the command has been started.
+func (c *MockCommand) StdoutPipe() (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done != nil {
+		return nil, errors.New("exec: StdoutPipe after process started")
+	}
+
+	c.stdoutPipe = newBufferedPipe()
+	return &pipeReader{c.stdoutPipe}, nil
+}
+
+// StderrPipe implements the [Cmd] interface, see [Cmd.StderrPipe] for
+// more information. The returned reader contains the data from
+// [MockCommand.Stderr] once the command has been started.
+func (c *MockCommand) StderrPipe() (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done != nil {
+		return nil, errors.New("exec: StderrPipe after process started")
+	}
+
+	c.stderrPipe = newBufferedPipe()
+	return &pipeReader{c.stderrPipe}, nil
+}
+
+// StdinPipe implements the [Cmd] interface, see [Cmd.StdinPipe] for
+// more information. Data written to the returned writer is used as the
+// stdin of the command and checked against [MockCommand.Stdin], if
+// set. The writer must be closed before the command can finish reading
+// stdin.
+func (c *MockCommand) StdinPipe() (io.WriteCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done != nil {
+		return nil, errors.New("exec: StdinPipe after process started")
+	}
+
+	c.stdinPipe = newBufferedPipe()
+	c.stdin = &pipeReader{c.stdinPipe}
+	c.stdinBytes = nil
+	return &pipeWriter{c.stdinPipe}, nil
+}
+
+// String implements the [Cmd] interface, see [Cmd.String] for more
+// information.
+func (c *MockCommand) String() string {
+	return strings.Join(append([]string{c.Path()}, c.Argv()[1:]...), " ")
+}
+
+// Path implements the [Cmd] interface, see [Cmd.Path] for more
+// information. Like [MockCommand.Argv], it is derived from the name the
+// command was called with, which Name may not be set to (e.g., for
+// [MockExecutor.SetDefault]).
+func (c *MockCommand) Path() string {
+	return lookPath(c.Argv()[0])
+}
+
+// lookPath returns the full path to the command name if it can be found
+// in the PATH, or name otherwise. This is mostly to match the behavior
+// of [exec.Command].
+func lookPath(name string) string {
+	if realPath, err := exec.LookPath(name); err == nil {
+		return realPath
+	}
+	return name
+}
+
+// Argv implements the [Cmd] interface, see [Cmd.Argv] for more
+// information.
+func (c *MockCommand) Argv() []string {
+	c = c.current()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.argvLocked()
+}
+
+// argvLocked implements Argv. It must be called with c.mu held.
+func (c *MockCommand) argvLocked() []string {
+	if c.argv != nil {
+		return append([]string(nil), c.argv...)
+	}
+	return append([]string{c.Name}, c.Args...)
+}
+
+// Environ implements the [Cmd] interface, see [Cmd.Environ] for more
+// information. Like [exec.Cmd.Environ], if [MockCommand.SetEnviron] was
+// not called (or called with nil) the environment of the current
+// process is returned.
+func (c *MockCommand) Environ() []string {
+	if c.env == nil {
+		return os.Environ()
+	}
+	return append([]string(nil), c.env...)
+}
+
+// Clone implements the [Cmd] interface, see [Cmd.Clone] for more
+// information. The clone is a new invocation of the same mock, as if
+// created by the [MockExecutor], configured like c (e.g., through
+// [MockCommand.SetDir]) but not started.
+func (c *MockCommand) Clone() Cmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	argv := c.argvLocked()
+	clone := c.invoke(c.ctx, argv[0], argv[1:])
+	clone.dir = c.dir
+	clone.stdin = c.stdin
+	clone.stdinBytes = c.stdinBytes
+	clone.env = slices.Clone(c.env)
+	if c.sysProcAttr != nil {
+		attr := *c.sysProcAttr
+		clone.sysProcAttr = &attr
+	}
+	clone.extraFiles = slices.Clone(c.extraFiles)
+	clone.credential = c.credential
+	clone.nice = c.nice
+	clone.osStreams = c.osStreams
+	clone.rlimits = c.rlimits
+	clone.newProcessGroup = c.newProcessGroup
+	clone.ptySize = c.ptySize
+	clone.timeout = c.timeout
+	clone.onStdoutLine = c.onStdoutLine
+	clone.onStderrLine = c.onStderrLine
+	clone.stdoutFile = c.stdoutFile
+	clone.stderrFile = c.stderrFile
+	clone.maxOutputBytes = c.maxOutputBytes
+	clone.stdout = c.stdout
+	clone.stderr = c.stderr
+	clone.teeStdout = c.teeStdout
+	clone.teeStderr = c.teeStderr
+	clone.cancel = c.cancel
+	clone.cancelSignal = c.cancelSignal
+	clone.waitDelay = c.waitDelay
+	return clone
+}
+
+// SetEnviron implements the [Cmd] interface. For the MockCommand, the
+// environment is only recorded, see [MockCommand.CapturedEnv],
+// [MockCommand.CapturedEnvironCalls] and [MockCommand.ExpectedEnv].
+func (c *MockCommand) SetEnviron(env []string) {
+	root := c.root()
+	root.statsMu.Lock()
+	root.environCalls = append(root.environCalls, slices.Clone(env))
+	root.statsMu.Unlock()
+
+	c.env = env
+}
+
+// AppendEnv implements the [Cmd] interface, see [Cmd.AppendEnv] for
+// more information. For the MockCommand, the resulting environment is
+// only recorded and returned by [MockCommand.Environ].
+func (c *MockCommand) AppendEnv(kv ...string) {
+	c.env = mergeEnv(c.Environ(), kv...)
+}
+
+// SetEnv implements the [Cmd] interface, see [Cmd.SetEnv] for more
+// information.
+func (c *MockCommand) SetEnv(key, value string) {
+	c.AppendEnv(key + "=" + value)
+}
+
+// ScrubEnv implements the [Cmd] interface, see [Cmd.ScrubEnv] for more
+// information. For the MockCommand, the resulting environment is only
+// recorded and returned by [MockCommand.Environ].
+func (c *MockCommand) ScrubEnv(patterns ...string) {
+	c.env = scrubEnv(c.Environ(), patterns...)
+}
+
+// SetDir implements the [Cmd] interface. For the MockCommand, the
+// directory is only recorded (see [MockCommand.CapturedDir] and
+// [MockCommand.ExpectedDir]) because we do not actually execute any
+// commands.
+func (c *MockCommand) SetDir(dir string) {
+	c.dir = dir
+}
+
+// SetSysProcAttr implements the [Cmd] interface. For the MockCommand,
+// the attributes are recorded for inspection through
+// [MockCommand.CapturedSysProcAttr], but are otherwise not used.
+func (c *MockCommand) SetSysProcAttr(attr *syscall.SysProcAttr) {
+	c.sysProcAttr = attr
+}
+
+// CapturedSysProcAttr returns the attributes provided to
+// [MockCommand.SetSysProcAttr], or nil if it was never called.
+func (c *MockCommand) CapturedSysProcAttr() *syscall.SysProcAttr {
+	c = c.current()
+	return c.sysProcAttr
+}
+
+// UsePTY implements the [Cmd] interface. For the MockCommand, the
+// output of the command ([MockCommand.Stdout] followed by
+// [MockCommand.Stderr]) is provided through [MockCommand.PTY] and
+// anything written to it is recorded for inspection through
+// [MockCommand.CapturedPTYInput].
+func (c *MockCommand) UsePTY(rows, cols uint16) {
+	c.ptySize = &[2]uint16{rows, cols}
+}
+
+// PTY implements the [Cmd] interface, see [Cmd.PTY] for more
+// information.
+func (c *MockCommand) PTY() io.ReadWriter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pty == nil {
+		return nil
+	}
+	return c.pty
+}
+
+// ResizePTY implements the [Cmd] interface, see [Cmd.ResizePTY] for
+// more information. For the MockCommand, the size is recorded for
+// inspection through [MockCommand.CapturedPTYSize].
+func (c *MockCommand) ResizePTY(rows, cols uint16) error {
+	c.mu.Lock()
+	p := c.pty
+	c.mu.Unlock()
+
+	if p == nil {
+		return errNoPTY
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.size = [2]uint16{rows, cols}
+	return nil
+}
+
+// CapturedPTYSize returns the size of the PTY of the current (or last)
+// invocation of the command, including changes made through
+// [MockCommand.ResizePTY]. Before the command has been started, the
+// size requested through [MockCommand.UsePTY] is returned. Zero is
+// returned if UsePTY was never called.
+func (c *MockCommand) CapturedPTYSize() (rows, cols uint16) {
+	c = c.current()
+
+	c.mu.Lock()
+	p := c.pty
+	c.mu.Unlock()
+
+	if p != nil {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.size[0], p.size[1]
+	}
+
+	if c.ptySize == nil {
+		return 0, 0
+	}
+	return c.ptySize[0], c.ptySize[1]
+}
+
+// CapturedPTYInput returns the data written to the PTY of the current
+// (or last) invocation of the command.
+func (c *MockCommand) CapturedPTYInput() []byte {
+	c = c.current()
+
+	c.mu.Lock()
+	p := c.pty
+	c.mu.Unlock()
+
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]byte(nil), p.in.Bytes()...)
+}
+
+// SetExtraFiles implements the [Cmd] interface. For the MockCommand,
+// the files are recorded for inspection through
+// [MockCommand.CapturedExtraFiles], but are otherwise not used.
+func (c *MockCommand) SetExtraFiles(files []*os.File) {
+	c.extraFiles = files
+}
+
+// CapturedExtraFiles returns the files provided to
+// [MockCommand.SetExtraFiles], or nil if it was never called.
+func (c *MockCommand) CapturedExtraFiles() []*os.File {
+	c = c.current()
+	return c.extraFiles
+}
+
+// SetCancel implements the [Cmd] interface. The provided function is
+// called by [MockCommand.SimulateCancel].
+func (c *MockCommand) SetCancel(fn func() error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cancel, c.cancelSignal = fn, nil
+}
+
+// SetCancelSignal implements the [Cmd] interface, see
+// [Cmd.SetCancelSignal] for more information. The signal is sent by
+// [MockCommand.SimulateCancel], and recorded like any other signal
+// (see [MockCommand.CapturedSignals]).
+func (c *MockCommand) SetCancelSignal(sig os.Signal, killAfter time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cancel, c.cancelSignal = nil, sig
+	c.waitDelay = killAfter
+}
+
+// SetTimeout implements the [Cmd] interface. If the command is still
+// running once the timeout has elapsed (e.g., because of
+// [MockCommand.Delay] or [MockCommand.Blocking]), it is canceled and
+// fails with an error wrapping [context.DeadlineExceeded].
+func (c *MockCommand) SetTimeout(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.timeout = d
+}
+
+// SetWaitDelay implements the [Cmd] interface. For the MockCommand, the
+// delay is recorded for inspection through
+// [MockCommand.CapturedWaitDelay], but is otherwise not used.
+func (c *MockCommand) SetWaitDelay(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.waitDelay = d
+}
+
+// CapturedWaitDelay returns the delay provided to
+// [MockCommand.SetWaitDelay].
+func (c *MockCommand) CapturedWaitDelay() time.Duration {
+	c = c.current()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.waitDelay
+}
+
+// SimulateCancel simulates the context of the running command being
+// done. Like [exec.Cmd], the function set by [MockCommand.SetCancel] is
+// called, or [MockCommand.Kill] if one was not set, and its error is
+// returned. [MockCommand.Wait] then returns an error wrapping
+// [context.Canceled], like [Cmd.Wait] does for a canceled command, or
+// the error returned by the cancel function.
+func (c *MockCommand) SimulateCancel() error {
+	c = c.current()
+	return c.simulateCancel(context.Canceled)
+}
+
+// simulateCancel implements SimulateCancel, with cause being the error
+// of the context that was done.
+func (c *MockCommand) simulateCancel(cause error) error {
+	c.mu.Lock()
+	if c.done == nil {
+		c.mu.Unlock()
+		return errors.New("exec: not started")
+	}
+
+	cancel := c.cancel
+	if sig := c.cancelSignal; sig != nil {
+		cancel = func() error { return signalOrKill(c, sig) }
+	}
+	if cancel == nil {
+		cancel = c.Kill
+	}
+	c.mu.Unlock()
+
+	err := cancel()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.canceled, c.cancelErr = cause, err
+
+	return err
+}
+
+// SetStdout implements the [Cmd] interface. For the MockCommand, the
+// mocked stdout is written to w once the command runs.
+func (c *MockCommand) SetStdout(w io.Writer) {
+	c.stdout = w
+}
+
+// SetStderr implements the [Cmd] interface. For the MockCommand, the
+// mocked stderr is written to w once the command runs.
+func (c *MockCommand) SetStderr(w io.Writer) {
+	c.stderr = w
+}
+
+// SetStdin sets the stdin of the command to the given reader. This is
+// used for validation purposes to ensure that the provided stdin
+// matches what was expected.
+func (c *MockCommand) SetStdin(r io.Reader) {
+	c.stdin = r
+	c.stdinBytes = nil
+}
+
+// SetStdinString implements the [Cmd] interface, see
+// [Cmd.SetStdinString] for more information. Unlike SetStdin, the
+// provided input is checked against [MockCommand.Stdin] on every run.
+func (c *MockCommand) SetStdinString(s string) {
+	c.SetStdinBytes([]byte(s))
+}
+
+// SetStdinBytes implements the [Cmd] interface, see
+// [Cmd.SetStdinBytes] for more information. Unlike SetStdin, the
+// provided input is checked against [MockCommand.Stdin] on every run.
+func (c *MockCommand) SetStdinBytes(b []byte) {
+	c.stdin = nil
+	c.stdinBytes = append([]byte{}, b...)
+}
+
+// OnStdoutLine implements the [Cmd] interface, see [Cmd.OnStdoutLine]
+// for more information. For the MockCommand, fn is called with every
+// line of the mocked stdout once the command runs.
+func (c *MockCommand) OnStdoutLine(fn func(string)) {
+	c.onStdoutLine = fn
+}
+
+// OnStderrLine implements the [Cmd] interface, see [Cmd.OnStderrLine]
+// for more information. For the MockCommand, fn is called with every
+// line of the mocked stderr once the command runs.
+func (c *MockCommand) OnStderrLine(fn func(string)) {
+	c.onStderrLine = fn
+}
+
+// SetStdoutFile implements the [Cmd] interface, see
+// [Cmd.SetStdoutFile] for more information. For the MockCommand, the
+// mocked stdout is written to the file once the command runs.
+func (c *MockCommand) SetStdoutFile(path string, mode WriteMode) {
+	c.stdoutFile = &outputFile{path: path, mode: mode}
+}
+
+// SetStderrFile implements the [Cmd] interface, see
+// [Cmd.SetStderrFile] for more information. For the MockCommand, the
+// mocked stderr is written to the file once the command runs.
+func (c *MockCommand) SetStderrFile(path string, mode WriteMode) {
+	c.stderrFile = &outputFile{path: path, mode: mode}
+}
+
+// SetMaxOutputBytes implements the [Cmd] interface, see
+// [Cmd.SetMaxOutputBytes] for more information. The limit is applied to
+// the mocked output.
+func (c *MockCommand) SetMaxOutputBytes(n int64) {
+	c.maxOutputBytes = n
+}
+
+// limitOutput applies the limit set through SetMaxOutputBytes to out.
+func (c *MockCommand) limitOutput(out []byte, err error) ([]byte, error) {
+	b := &limitedBuffer{max: c.maxOutputBytes}
+	b.Write(out) //nolint:errcheck // Why: Never fails.
+	return b.Bytes(), withTruncated(err, b.truncated)
+}
+
+// TeeStdout implements the [Cmd] interface, see [Cmd.TeeStdout] for
+// more information. For the MockCommand, the mocked stdout is written
+// to w once the command runs.
+func (c *MockCommand) TeeStdout(w io.Writer) {
+	c.teeStdout = w
+}
+
+// TeeStderr implements the [Cmd] interface, see [Cmd.TeeStderr] for
+// more information. For the MockCommand, the mocked stderr is written
+// to w once the command runs.
+func (c *MockCommand) TeeStderr(w io.Writer) {
+	c.teeStderr = w
+}
+
+// UseOSStreams implements the [Cmd] interface. For the MockCommand,
+// the call is recorded for inspection through
+// [MockCommand.CapturedOSStreams], but the OS streams are not used to
+// avoid writing to the output of the test.
+func (c *MockCommand) UseOSStreams(stdin bool) {
+	c.osStreams = &stdin
+}
+
+// CapturedOSStreams returns if UseOSStreams was called (ok) and whether
+// stdin was requested.
+func (c *MockCommand) CapturedOSStreams() (stdin, ok bool) {
+	c = c.current()
+
+	if c.osStreams == nil {
+		return false, false
+	}
+	return *c.osStreams, true
+}
+
+// NewMockExecutor returns a new MockExecutor with the given commands. A
+// [MockExecutor] contains various commands that should be mocked
+// instead of actually executed.
+//
+// Once a [MockExecutor] is created and used with [UseMockExecutor], it
+// will error if any commands are executed that were not registered with
+// the executor.
+//
+// Commands that have had SetStdin() called and set Stdin data will also
+// have their stdin checked to ensure that the provided input matches
+// the expected input, this enables testing of commands that read from
+// stdin.
+func NewMockExecutor(cmds ...*MockCommand) *MockExecutor {
+	me := &MockExecutor{}
+	me.cmds = make(map[string]*MockCommand)
+	for _, cmd := range cmds {
+		me.AddCommand(cmd)
+	}
+	return me
+}
+
+// getCommandKey returns a unique key for a command based on its name
+// and arguments. Every word is prefixed by its length so that, e.g., a
+// single argument containing a space doesn't collide with two separate
+// arguments. The key is built with a single allocation.
+func (e *MockExecutor) getCommandKey(name string, args ...string) string {
+	name = e.normalizeName(name)
+
+	// Reserve enough room for lengths of up to 99999 bytes and their
+	// separators.
+	size := len(name) + 6
+	for _, arg := range args {
+		size += len(arg) + 6
+	}
+
+	var key strings.Builder
+	key.Grow(size)
+	writeKeyWord(&key, name)
+	for _, arg := range args {
+		key.WriteByte(' ')
+		writeKeyWord(&key, arg)
+	}
+	return key.String()
+}
+
+// writeKeyWord writes word prefixed by its length to key, see
+// getCommandKey.
+func writeKeyWord(key *strings.Builder, word string) {
+	key.WriteString(strconv.Itoa(len(word)))
+	key.WriteByte(':')
+	key.WriteString(word)
+}
+
+// AddCommand adds a command to the executor. If the command has
+// already been added, it will be replaced. Commands with
+// [MockCommand.AnyArgs] or [MockCommand.ArgPatterns] are matched in the
+// order they were added.
+func (e *MockExecutor) AddCommand(cmd *MockCommand) {
+	if matchArgs := cmd.argsMatcher(); matchArgs != nil {
+		e.AddMatch(MatcherFunc(func(name string, args []string) bool {
+			return e.sameName(name, cmd.Name) && matchArgs(args)
+		}), cmd)
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cmd.executor = e
+	e.cmds[e.getCommandKey(cmd.Name, cmd.Args...)] = cmd
+}
+
+// AddCommandString adds a command to the executor, setting its Name and
+// Args from the provided command line. The command line is split the
+// same way as [CommandString] does.
+func (e *MockExecutor) AddCommandString(cmdline string, cmd *MockCommand) error {
+	words, err := splitCommandLine(cmdline)
+	if err != nil {
+		return err
+	}
+
+	cmd.Name, cmd.Args = words[0], words[1:]
+	e.AddCommand(cmd)
+	return nil
+}
+
+// AddCommandPattern adds a command to the executor that is used for
+// every command whose command line (its name and arguments joined by
+// spaces) matches re, e.g.:
+//
+//	mock.AddCommandPattern(regexp.MustCompile(`^git checkout [0-9a-f]{40}$`), &cmdexec.MockCommand{})
+//
+// Like [regexp.Regexp.MatchString], re matches anywhere in the command
+// line unless anchored. The Name and Args of cmd are ignored. Commands
+// registered with exact arguments take precedence, otherwise commands
+// are matched in the order they were added.
+func (e *MockExecutor) AddCommandPattern(re *regexp.Regexp, cmd *MockCommand) {
+	e.AddMatch(MatcherFunc(func(name string, args []string) bool {
+		return re.MatchString(strings.Join(append([]string{name}, args...), " "))
+	}), cmd)
+}
+
+// AddCommandPrefix adds a command to the executor that is used for
+// every command with the same Name whose arguments start with Args,
+// e.g., a command with the Name "kubectl" and Args []string{"get"}
+// matches all "kubectl get ..." commands. The arguments a command was
+// called with are available through [MockCommand.Argv]. Commands
+// registered with exact arguments take precedence, otherwise commands
+// are matched in the order they were added.
+func (e *MockExecutor) AddCommandPrefix(cmd *MockCommand) {
+	e.AddMatch(MatcherFunc(func(name string, args []string) bool {
+		return e.sameName(name, cmd.Name) && len(args) >= len(cmd.Args) && slices.Equal(args[:len(cmd.Args)], cmd.Args)
+	}), cmd)
+}
+
+// AddNotFound registers name as an executable that doesn't exist,
+// regardless of the arguments it is called with, see
+// [MockCommand.NotFound].
+func (e *MockExecutor) AddNotFound(name string) {
+	e.AddCommand(&MockCommand{Name: name, AnyArgs: true, NotFound: true})
+}
+
+// AddMatch adds a command to the executor that is used for every
+// command matched by m, allowing arbitrary matching logic, e.g.:
+//
+//	mock.AddMatch(cmdexec.MatcherFunc(func(name string, args []string) bool {
+//	    return name == "terraform" && slices.Contains(args, "-dry-run")
+//	}), &cmdexec.MockCommand{})
+//
+// The Name and Args of cmd are ignored. Commands registered with exact
+// arguments take precedence, otherwise commands are matched in the order
+// they were added.
+func (e *MockExecutor) AddMatch(m Matcher, cmd *MockCommand) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cmd.executor = e
+	e.matchers = append(e.matchers, mockMatcher{matcher: m, cmd: cmd})
+}
+
+// RemoveCommand removes the command with the given name and arguments
+// from the executor, including commands added with
+// [MockCommand.AnyArgs] or [MockCommand.ArgPatterns] with the same Name
+// and Args. It reports whether a command was removed.
+func (e *MockExecutor) RemoveCommand(name string, args ...string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := e.getCommandKey(name, args...)
+	_, removed := e.cmds[key]
+	delete(e.cmds, key)
+
+	n := len(e.matchers)
+	e.matchers = slices.DeleteFunc(e.matchers, func(m mockMatcher) bool {
+		return e.sameName(m.cmd.Name, name) && slices.Equal(m.cmd.Args, args)
+	})
+	return removed || len(e.matchers) != n
+}
+
+// Reset removes all commands from the executor, including the ones set
+// through SetDefault and SetDefaultFor, and clears the recorded calls (see
+// [MockExecutor.Calls]). Options such as Passthrough are kept.
+func (e *MockExecutor) Reset() {
+	e.mu.Lock()
+	e.cmds = make(map[string]*MockCommand)
+	e.matchers = nil
+	e.defaultCmd = nil
+	e.nameDefaults = nil
+	e.scenarios = nil
+	e.mu.Unlock()
+
+	e.callsMu.Lock()
+	e.calls, e.attempts = nil, nil
+	e.callsMu.Unlock()
+}
+
+// CaseInsensitiveNames makes the executor match command names
+// regardless of their case and of a Windows executable extension
+// (".exe", ".com", ".bat", or ".cmd"), so that "Git.EXE", "git.exe",
+// and "git" are the same command, like they are on Windows. This is
+// always the case on Windows. It returns the executor to allow
+// chaining.
+func (e *MockExecutor) CaseInsensitiveNames() *MockExecutor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.caseInsensitiveNames = true
+
+	// Commands that were already added must be found by their new key.
+	cmds := e.cmds
+	e.cmds = make(map[string]*MockCommand, len(cmds))
+	for _, cmd := range cmds {
+		e.cmds[e.getCommandKey(cmd.Name, cmd.Args...)] = cmd
+	}
+	return e
+}
+
+// normalizeName returns the name of a command as it should be compared,
+// see CaseInsensitiveNames. This must be called with e.mu held.
+func (e *MockExecutor) normalizeName(name string) string {
+	if !e.caseInsensitiveNames && runtime.GOOS != "windows" {
+		return name
+	}
+
+	name = strings.ToLower(name)
+	for _, ext := range []string{".exe", ".com", ".bat", ".cmd"} {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return name
+}
+
+// sameName reports whether a and b name the same command, see
+// CaseInsensitiveNames. This must be called with e.mu held.
+func (e *MockExecutor) sameName(a, b string) bool {
+	return e.normalizeName(a) == e.normalizeName(b)
+}
+
+// Passthrough makes commands that weren't registered with the executor
+// execute for real, instead of panicking. This allows mocking only some
+// commands (e.g., "terraform apply") while letting harmless ones (e.g.,
+// "git rev-parse") run. It returns the executor to allow chaining.
+func (e *MockExecutor) Passthrough() *MockExecutor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.passthrough = true
+	return e
+}
+
+// SetDefault sets the command used for every command that wasn't
+// registered with the executor, instead of panicking. For example, to
+// make every other command succeed without any output:
+//
+//	mock.SetDefault(&cmdexec.MockCommand{})
+//
+// The Name and Args of cmd are ignored. The arguments a command was
+// called with are available through [MockCommand.Argv].
+func (e *MockExecutor) SetDefault(cmd *MockCommand) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cmd.executor = e
+	e.defaultCmd = cmd
+}
+
+// SetDefaultFor sets the command used for every command named name
+// that wasn't registered with the executor, e.g., to stub a read-only
+// probe regardless of its arguments while specific invocations are
+// still mocked precisely:
+//
+//	mock.SetDefaultFor("git", &cmdexec.MockCommand{Stdout: []byte("/repo\n")})
+//
+// Unlike [MockCommand.AnyArgs], it is only used once all other
+// registered commands (including patterns) failed to match, but before
+// the command set through SetDefault. The Name and Args of cmd are
+// ignored.
+func (e *MockExecutor) SetDefaultFor(name string, cmd *MockCommand) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cmd.executor = e
+	if e.nameDefaults == nil {
+		e.nameDefaults = make(map[string]*MockCommand)
+	}
+	e.nameDefaults[name] = cmd
+}
+
+// defaultFor returns the command set through SetDefaultFor for name, if
+// any. This must be called with e.mu held.
+func (e *MockExecutor) defaultFor(name string) *MockCommand {
+	for n, cmd := range e.nameDefaults {
+		if e.sameName(n, name) {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// ErrorOnUnregistered makes commands that weren't registered with the
+// executor fail to start with an error wrapping [ErrNotRegistered],
+// instead of panicking. This is useful when commands are created in a
+// goroutine, where a panic would crash the entire test binary. It
+// returns the executor to allow chaining.
+func (e *MockExecutor) ErrorOnUnregistered() *MockExecutor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.errorOnUnregistered = true
+	return e
+}
+
+// executor implements the [executorFn] type, returning a Cmd based on
+// the provided arguments. If no commands are available based on the
+// provided input, the default command set through SetDefault is used.
+// Otherwise, this function panics unless Passthrough or
+// ErrorOnUnregistered was called.
+func (e *MockExecutor) executor(ctx context.Context, name string, arg ...string) Cmd {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	key := e.getCommandKey(name, arg...)
+	if cmd, ok := e.cmds[key]; ok {
+		return cmd.invoke(ctx, name, arg)
+	}
+
+	for _, s := range e.scenarios {
+		if cmd := s.lookup(name, arg); cmd != nil {
+			return cmd.invoke(ctx, name, arg)
+		}
+	}
+
+	for _, m := range e.matchers {
+		if m.matcher.Match(name, arg) {
+			return m.cmd.invoke(ctx, name, arg)
+		}
+	}
+
+	if cmd := e.defaultFor(name); cmd != nil {
+		return cmd.invoke(ctx, name, arg)
+	}
+
+	if e.defaultCmd != nil {
+		return e.defaultCmd.invoke(ctx, name, arg)
+	}
+
+	if e.passthrough {
+		return stdExecutor(ctx, name, arg...)
+	}
+
+	err := fmt.Errorf("%w for '%s %s' missing call to MockExecutor.AddCommand?",
+		ErrNotRegistered, name, strings.Join(arg, " "),
+	)
+	if e.errorOnUnregistered {
+		return &MockCommand{Name: name, Args: arg, startErr: err, executor: e}
+	}
+	panic(err)
 }
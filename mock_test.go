@@ -1,14 +1,30 @@
 package cmdexec_test
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/jaredallard/cmdexec"
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
 )
 
 // TestCanMockACommand ensures that if we mock a command, it actually
@@ -50,6 +66,289 @@ func TestCanMockStdin(t *testing.T) {
 	assert.Error(t, err, fmt.Sprintf("expected stdin set by SetStdin() to be %q but got %q", "hello world", "goodbye world"))
 }
 
+// TestCanMockStdinString ensures that stdin set with SetStdinString is
+// validated on every run of the command.
+func TestCanMockStdinString(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:  "cat",
+		Stdin: []byte("hello world"),
+	}))
+
+	cmd := cmdexec.Command("cat")
+	cmd.SetStdinString("hello world")
+	assert.NilError(t, cmd.Run())
+	assert.NilError(t, cmd.Run())
+
+	cmd.SetStdinBytes([]byte("goodbye world"))
+	assert.Error(t, cmd.Run(), fmt.Sprintf("expected stdin set by SetStdin() to be %q but got %q", "hello world", "goodbye world"))
+}
+
+// TestMockStdinDiff ensures that multi-line stdin that doesn't match
+// is reported as a unified diff, leaving out unchanged lines.
+func TestMockStdinDiff(t *testing.T) {
+	var want, got strings.Builder
+	for i := range 20 {
+		fmt.Fprintf(&want, "line %d\n", i)
+		switch i {
+		case 2:
+			got.WriteString("changed\n")
+		case 15:
+		default:
+			fmt.Fprintf(&got, "line %d\n", i)
+		}
+	}
+	got.WriteString("extra")
+
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:  "cat",
+		Stdin: []byte(want.String()),
+	}))
+
+	cmd := cmdexec.Command("cat")
+	cmd.SetStdinString(got.String())
+	assert.Error(t, cmd.Run(), `expected stdin set by SetStdin() to match, but it differs:
+--- expected
++++ got
+@@ -1,6 +1,6 @@
+ line 0
+ line 1
+-line 2
++changed
+ line 3
+ line 4
+ line 5
+@@ -13,8 +13,8 @@
+ line 12
+ line 13
+ line 14
+-line 15
+ line 16
+ line 17
+ line 18
+ line 19
++extra
+\ No newline at end of file`)
+}
+
+// TestMockStdinDiffLarge ensures that diffing large, mostly different
+// stdin doesn't need memory quadratic in its number of lines.
+func TestMockStdinDiffLarge(t *testing.T) {
+	var want, got strings.Builder
+	for i := range 5000 {
+		fmt.Fprintf(&want, "want %d\n", i)
+		fmt.Fprintf(&got, "got %d\n", i)
+	}
+
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:  "cat",
+		Stdin: []byte(want.String()),
+	}))
+
+	cmd := cmdexec.Command("cat")
+	cmd.SetStdinString(got.String())
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	err := cmd.Run()
+	runtime.ReadMemStats(&after)
+
+	assert.ErrorContains(t, err, "--- expected\n+++ got\n@@ -1,5000 +1,5000 @@\n-want 0\n")
+	assert.ErrorContains(t, err, "\n+got 4999")
+	assert.Assert(t, after.TotalAlloc-before.TotalAlloc < 32<<20, "allocated %d bytes", after.TotalAlloc-before.TotalAlloc)
+}
+
+// TestMockStdinMatcher ensures that stdin can be checked by a function
+// instead of being compared byte-for-byte.
+func TestMockStdinMatcher(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name: "sh",
+		StdinMatcher: func(stdin []byte) error {
+			if !bytes.HasPrefix(stdin, []byte("#!")) {
+				return errors.New("missing shebang")
+			}
+			return nil
+		},
+	}))
+
+	cmd := cmdexec.Command("sh")
+	assert.Error(t, cmd.Run(), "expected stdin to be provided but it was not (was SetStdin() called?)")
+
+	cmd.SetStdinString("#!/bin/sh\necho " + time.Now().String())
+	assert.NilError(t, cmd.Run())
+
+	cmd.SetStdinString("echo hello")
+	assert.Error(t, cmd.Run(), "stdin set by SetStdin() didn't match: missing shebang")
+}
+
+// TestMockStdinContains ensures that stdin can be checked for
+// fragments instead of being compared byte-for-byte.
+func TestMockStdinContains(t *testing.T) {
+	mock := cmdexec.NewMockExecutor()
+	mock.Expect("kubectl", "apply", "-f", "-").WithStdinContaining("kind: Deployment", "replicas: 3")
+	cmdexec.UseMockExecutor(t, mock)
+
+	cmd := cmdexec.Command("kubectl", "apply", "-f", "-")
+	cmd.SetStdinString("apiVersion: apps/v1\nkind: Deployment\nspec:\n  replicas: 3\n")
+	assert.NilError(t, cmd.Run())
+
+	cmd.SetStdinString("kind: Deployment\n")
+	assert.Error(t, cmd.Run(), `expected stdin set by SetStdin() to contain "replicas: 3" but got "kind: Deployment\n"`)
+}
+
+// TestMockStdinJSON ensures that stdin can be compared as JSON, with
+// the differences reported on mismatch.
+func TestMockStdinJSON(t *testing.T) {
+	mock := cmdexec.NewMockExecutor()
+	mock.Expect("kubectl", "apply", "-f", "-").WithStdinJSON(`{"kind":"Pod","spec":{"replicas":3,"ports":[80,443]}}`)
+	cmdexec.UseMockExecutor(t, mock)
+
+	cmd := cmdexec.Command("kubectl", "apply", "-f", "-")
+	cmd.SetStdinString(`{"spec": {"ports": [80, 443], "replicas": 3.0}, "kind": "Pod"}` + "\n")
+	assert.NilError(t, cmd.Run())
+
+	cmd.SetStdinString(`{"kind":"Deployment","spec":{"replicas":2,"ports":[80]},"status":{}}`)
+	assert.Error(t, cmd.Run(), strings.Join([]string{
+		"expected stdin set by SetStdin() to match the JSON in Stdin, but it differs:",
+		`$.kind: expected "Pod" but got "Deployment"`,
+		"$.spec.ports[1]: missing, expected 443",
+		"$.spec.replicas: expected 3 but got 2",
+		"$.status: unexpected {}",
+	}, "\n\t"))
+
+	cmd.SetStdinString(`{"kind":`)
+	assert.Error(t, cmd.Run(), "expected stdin set by SetStdin() to be JSON but failed to parse it: unexpected EOF")
+}
+
+// TestMockCapturedStdin ensures that the stdin read by a mocked command
+// can be retrieved once it has run.
+func TestMockCapturedStdin(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name: "kubectl",
+		Args: []string{"apply", "-f", "-"},
+	}))
+
+	cmd := cmdexec.Command("kubectl", "apply", "-f", "-")
+	assert.Assert(t, cmd.(*cmdexec.MockCommand).CapturedStdin() == nil)
+
+	cmd.SetStdin(strings.NewReader(`{"kind":"Pod"}`))
+	assert.NilError(t, cmd.Run())
+
+	var manifest map[string]string
+	assert.NilError(t, json.Unmarshal(cmd.(*cmdexec.MockCommand).CapturedStdin(), &manifest))
+	assert.DeepEqual(t, manifest, map[string]string{"kind": "Pod"})
+}
+
+// TestMockEnv ensures that the environment of a mocked command is
+// captured and checked against ExpectedEnv.
+func TestMockEnv(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:        "go",
+		Args:        []string{"build"},
+		ExpectedEnv: []string{"CGO_ENABLED=0"},
+	}))
+
+	cmd := cmdexec.Command("go", "build")
+	assert.Assert(t, cmd.(*cmdexec.MockCommand).CapturedEnv() == nil)
+
+	cmd.SetEnviron([]string{"CGO_ENABLED=0", "GOOS=linux"})
+	assert.NilError(t, cmd.Run())
+	assert.DeepEqual(t, cmd.(*cmdexec.MockCommand).CapturedEnv(), []string{"CGO_ENABLED=0", "GOOS=linux"})
+
+	cmd.SetEnv("CGO_ENABLED", "1")
+	assert.Error(t, cmd.Run(), `expected environment to contain "CGO_ENABLED=0" but it did not (was SetEnviron() called?)`)
+
+	// Only the environment set for this invocation is checked.
+	t.Setenv("CGO_ENABLED", "1")
+	assert.Error(t, cmdexec.Command("go", "build").Run(), `expected environment to contain "CGO_ENABLED=0" but it did not (was SetEnviron() called?)`)
+}
+
+// TestMockDir ensures that the working directory of a mocked command
+// is captured and checked against ExpectedDir.
+func TestMockDir(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:        "git",
+		Args:        []string{"status"},
+		ExpectedDir: "/src/repo",
+	}))
+
+	cmd := cmdexec.Command("git", "status")
+	assert.Error(t, cmd.Run(), `expected working directory set by SetDir() to be "/src/repo" but got ""`)
+
+	cmd.SetDir("/src/repo/")
+	assert.NilError(t, cmd.Run())
+	assert.Equal(t, cmd.(*cmdexec.MockCommand).CapturedDir(), "/src/repo/")
+
+	// Only the directory set for this invocation is checked.
+	assert.Error(t, cmdexec.Command("git", "status").Run(), `expected working directory set by SetDir() to be "/src/repo" but got ""`)
+}
+
+// TestMockClone ensures that clones of a mocked command are
+// configured like the original, but independent of it.
+func TestMockClone(t *testing.T) {
+	mock := cmdexec.NewMockExecutor((&cmdexec.MockCommand{
+		Name:        "git",
+		Args:        []string{"status"},
+		Stdout:      []byte("clean\n"),
+		ExpectedDir: "/src/repo",
+	}).Times(3))
+	cmdexec.UseMockExecutor(t, mock)
+
+	cmd := cmdexec.Command("git", "status")
+	cmd.SetDir("/src/repo")
+	cmd.SetEnv("GIT_DIR", ".git")
+	assert.NilError(t, cmd.Run())
+
+	clone := cmd.Clone()
+	assert.Assert(t, clone != cmd)
+	out, err := clone.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "clean\n")
+	assert.Assert(t, slices.Contains(clone.(*cmdexec.MockCommand).CapturedEnv(), "GIT_DIR=.git"))
+
+	clone = cmd.Clone()
+	clone.SetDir("/src")
+	assert.Error(t, clone.Run(), `expected working directory set by SetDir() to be "/src/repo" but got "/src"`)
+	assert.Equal(t, cmd.(*cmdexec.MockCommand).CapturedDir(), "/src/repo")
+}
+
+// TestMockDirPattern ensures that the working directory of a command
+// can be checked against a pattern, e.g., for temporary directories.
+func TestMockDirPattern(t *testing.T) {
+	mock := cmdexec.NewMockExecutor(
+		&cmdexec.MockCommand{Name: "go", Args: []string{"build"}, ExpectedDirPattern: "*/TestMockDirPattern*/*"},
+		&cmdexec.MockCommand{Name: "go", Args: []string{"test"}, ExpectedDirRegexp: regexp.MustCompile(`/\d+$`)},
+	)
+	cmdexec.UseMockExecutor(t, mock)
+
+	dir := t.TempDir()
+	for _, args := range [][]string{{"build"}, {"test"}} {
+		cmd := cmdexec.Command("go", args...)
+		cmd.SetDir(dir)
+		assert.NilError(t, cmd.Run())
+	}
+
+	cmd := cmdexec.Command("go", "build")
+	cmd.SetDir("/src")
+	assert.Error(t, cmd.Run(), `expected working directory set by SetDir() to match pattern "*/TestMockDirPattern*/*" but got "/src"`)
+
+	cmd = cmdexec.Command("go", "test")
+	cmd.SetDir("/src")
+	assert.Error(t, cmd.Run(), `expected working directory set by SetDir() to match regexp "/\\d+$" but got "/src"`)
+}
+
+// TestMockCapturedOSStreams ensures that calls to UseOSStreams are
+// recorded.
+func TestMockCapturedOSStreams(t *testing.T) {
+	cmd := &cmdexec.MockCommand{}
+	_, ok := cmd.CapturedOSStreams()
+	assert.Equal(t, ok, false)
+
+	cmd.UseOSStreams(true)
+	stdin, ok := cmd.CapturedOSStreams()
+	assert.Equal(t, ok, true)
+	assert.Equal(t, stdin, true)
+}
+
 // TestCanReadCombinedOutput ensures that we can read the combined
 // output of a command.
 func TestCanReadCombinedOutput(t *testing.T) {
@@ -110,3 +409,1390 @@ func TestMockUnusedDontPanic(_ *testing.T) {
 	cmd.SetStdout(nil)
 	cmd.UseOSStreams(false)
 }
+
+// TestCanStartAndWaitMock ensures that a mocked command can be started
+// and waited on, and that the lifecycle errors match [exec.Cmd].
+func TestCanStartAndWaitMock(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name: "sleep",
+		Args: []string{"10"},
+		Err:  fmt.Errorf("exit status 1"),
+	}))
+
+	cmd := cmdexec.Command("sleep", "10")
+	assert.Error(t, cmd.Wait(), "exec: not started")
+
+	assert.NilError(t, cmd.Start())
+	assert.Error(t, cmd.Start(), "exec: already started")
+	assert.Error(t, cmd.Wait(), "exit status 1")
+
+	// Mocks can be ran again once they have finished.
+	assert.Error(t, cmd.Run(), "exit status 1")
+}
+
+// TestCanUseMockPipes ensures that the pipes of a mocked command
+// provide the mocked output and validate the provided input.
+func TestCanUseMockPipes(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:   "cat",
+		Stdout: []byte("hello"),
+		Stderr: []byte("world"),
+		Stdin:  []byte("hello world"),
+	}))
+
+	cmd := cmdexec.Command("cat")
+	stdout, err := cmd.StdoutPipe()
+	assert.NilError(t, err)
+	stderr, err := cmd.StderrPipe()
+	assert.NilError(t, err)
+	stdin, err := cmd.StdinPipe()
+	assert.NilError(t, err)
+
+	assert.NilError(t, cmd.Start())
+	_, err = cmd.StdoutPipe()
+	assert.Error(t, err, "exec: StdoutPipe after process started")
+
+	_, err = stdin.Write([]byte("hello "))
+	assert.NilError(t, err)
+	_, err = stdin.Write([]byte("world"))
+	assert.NilError(t, err)
+	assert.NilError(t, stdin.Close())
+
+	out, err := io.ReadAll(stdout)
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "hello")
+
+	errOut, err := io.ReadAll(stderr)
+	assert.NilError(t, err)
+	assert.Equal(t, string(errOut), "world")
+
+	assert.NilError(t, cmd.Wait())
+}
+
+// TestMockExitCode ensures that a mocked command reports the configured
+// exit code once it has finished.
+func TestMockExitCode(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:       "false",
+		ExitStatus: 2,
+	}))
+
+	cmd := cmdexec.Command("false")
+	assert.Equal(t, cmd.ExitCode(), -1)
+	err := cmd.Run()
+	assert.Error(t, err, "exit status 2")
+	assert.Equal(t, cmd.ExitCode(), 2)
+
+	var exitErr interface{ ExitCode() int }
+	assert.Assert(t, errors.As(err, &exitErr))
+	assert.Equal(t, exitErr.ExitCode(), 2)
+}
+
+// TestMockProcessState ensures that a mocked command reports a process
+// ID once started and synthesizes a process state once finished.
+func TestMockProcessState(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:       "false",
+		Pid:        1234,
+		ExitStatus: 1,
+	}))
+
+	cmd := cmdexec.Command("false")
+	assert.Equal(t, cmd.PID(), -1)
+	assert.Assert(t, cmd.ProcessState() == nil)
+
+	assert.Error(t, cmd.Run(), "exit status 1")
+	assert.Equal(t, cmd.PID(), 1234)
+	assert.DeepEqual(t, cmd.ProcessState(), &cmdexec.ProcessState{
+		Pid:      1234,
+		ExitCode: 1,
+		Exited:   true,
+	})
+}
+
+// TestMockRecordsSignals ensures that signals sent to a mocked command
+// are recorded.
+func TestMockRecordsSignals(t *testing.T) {
+	mock := &cmdexec.MockCommand{Name: "sleep", Args: []string{"10"}}
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(mock))
+
+	cmd := cmdexec.Command("sleep", "10")
+	assert.Error(t, cmd.Kill(), "exec: not started")
+
+	assert.NilError(t, cmd.Start())
+	assert.NilError(t, cmd.Signal(syscall.SIGTERM))
+	assert.NilError(t, cmd.Kill())
+	assert.NilError(t, cmd.Wait())
+	assert.ErrorIs(t, cmd.Kill(), os.ErrProcessDone)
+
+	assert.DeepEqual(t, mock.CapturedSignals(), []os.Signal{syscall.SIGTERM, os.Kill})
+}
+
+// TestMockCapturesSysProcAttr ensures that the attributes set through
+// SetSysProcAttr are recorded by mocks.
+func TestMockCapturesSysProcAttr(t *testing.T) {
+	mock := &cmdexec.MockCommand{Name: "echo"}
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(mock))
+
+	attr := &syscall.SysProcAttr{}
+	cmdexec.Command("echo").SetSysProcAttr(attr)
+	assert.Equal(t, mock.CapturedSysProcAttr(), attr)
+}
+
+// TestMockSimulateCancel ensures that simulating a canceled context
+// calls the configured cancel function and causes Wait to return
+// context.Canceled.
+func TestMockSimulateCancel(t *testing.T) {
+	mock := &cmdexec.MockCommand{Name: "sleep", Args: []string{"10"}}
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(mock))
+
+	cmd := cmdexec.Command("sleep", "10")
+	cmd.SetCancel(func() error { return cmd.Signal(os.Interrupt) })
+	cmd.SetWaitDelay(5 * time.Second)
+	assert.Equal(t, mock.CapturedWaitDelay(), 5*time.Second)
+
+	assert.NilError(t, cmd.Start())
+	assert.NilError(t, mock.SimulateCancel())
+	assert.ErrorIs(t, cmd.Wait(), context.Canceled)
+	assert.DeepEqual(t, mock.CapturedSignals(), []os.Signal{os.Interrupt})
+
+	// Without a cancel function, the command is killed.
+	cmd.SetCancel(nil)
+	assert.NilError(t, cmd.Start())
+	assert.NilError(t, mock.SimulateCancel())
+	assert.ErrorIs(t, cmd.Wait(), context.Canceled)
+	assert.DeepEqual(t, mock.CapturedSignals(), []os.Signal{os.Kill})
+}
+
+// TestMockEnviron ensures that the environment of a mocked command can
+// be read back.
+func TestMockEnviron(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{Name: "env"}))
+
+	cmd := cmdexec.Command("env")
+	assert.DeepEqual(t, cmd.Environ(), os.Environ())
+
+	cmd.SetEnviron([]string{"FOO=bar"})
+	assert.DeepEqual(t, cmd.Environ(), []string{"FOO=bar"})
+}
+
+// TestMockEnvironCalls ensures that every call of SetEnviron is
+// recorded, while the effective environment reflects all of them.
+func TestMockEnvironCalls(t *testing.T) {
+	mock := &cmdexec.MockCommand{Name: "env"}
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(mock))
+
+	cmd := cmdexec.Command("env")
+	env := []string{"FOO=bar"}
+	cmd.SetEnviron(env)
+	cmd.SetEnviron(append(cmd.Environ(), "BAZ=qux"))
+	cmd.AppendEnv("FOO=baz")
+	env[0] = "FOO=changed"
+	assert.NilError(t, cmd.Run())
+
+	assert.DeepEqual(t, mock.CapturedEnvironCalls(), [][]string{
+		{"FOO=bar"},
+		{"FOO=bar", "BAZ=qux"},
+	})
+	assert.DeepEqual(t, mock.CapturedEnv(), []string{"FOO=baz", "BAZ=qux"})
+}
+
+// TestAppendEnv ensures that AppendEnv and SetEnv merge into the
+// environment, with later keys taking precedence.
+func TestAppendEnv(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{Name: "env"}))
+
+	cmd := cmdexec.Command("env")
+	cmd.SetEnviron([]string{"FOO=bar", "BAZ=qux"})
+	cmd.AppendEnv("FOO=baz", "NEW=1", "NEW=2")
+	cmd.SetEnv("BAZ", "")
+	assert.DeepEqual(t, cmd.Environ(), []string{"FOO=baz", "BAZ=", "NEW=2"})
+}
+
+// TestScrubEnv ensures that variables matching the provided patterns
+// are removed from the environment.
+func TestScrubEnv(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{Name: "env"}))
+
+	cmd := cmdexec.Command("env")
+	cmd.SetEnviron([]string{"AWS_ACCESS_KEY_ID=a", "AWS_SECRET_ACCESS_KEY=b", "GITHUB_TOKEN=c", "HOME=/root"})
+	cmd.ScrubEnv("AWS_*", "GITHUB_TOKEN")
+	assert.DeepEqual(t, cmd.Environ(), []string{"HOME=/root"})
+}
+
+// TestMockPathAndArgv ensures that the path and arguments of a mocked
+// command match that of the standard library's exec.Cmd.
+func TestMockPathAndArgv(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name: "echo",
+		Args: []string{"hello", "world"},
+	}))
+
+	cmd := cmdexec.Command("echo", "hello", "world")
+	stdCmd := exec.Command("echo", "hello", "world")
+	assert.Equal(t, cmd.Path(), stdCmd.Path)
+	assert.DeepEqual(t, cmd.Argv(), stdCmd.Args)
+}
+
+// TestMockCapturesExtraFiles ensures that the files passed to a mocked
+// command are recorded.
+func TestMockCapturesExtraFiles(t *testing.T) {
+	mock := &cmdexec.MockCommand{Name: "server"}
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(mock))
+
+	cmdexec.Command("server").SetExtraFiles([]*os.File{os.Stdin})
+	files := mock.CapturedExtraFiles()
+	assert.Equal(t, len(files), 1)
+	assert.Equal(t, files[0], os.Stdin)
+}
+
+// TestMockPTY ensures that a mocked command using a PTY provides its
+// output through the PTY and records input written to it.
+func TestMockPTY(t *testing.T) {
+	mock := &cmdexec.MockCommand{
+		Name:   "login",
+		Stdout: []byte("Password: "),
+		Stderr: []byte("\r\nLogin incorrect\r\n"),
+	}
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(mock))
+
+	cmd := cmdexec.Command("login")
+	cmd.UsePTY(24, 80)
+	assert.NilError(t, cmd.Start())
+
+	_, err := cmd.PTY().Write([]byte("hunter2\r"))
+	assert.NilError(t, err)
+
+	out, err := io.ReadAll(cmd.PTY())
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "Password: \r\nLogin incorrect\r\n")
+	assert.NilError(t, cmd.Wait())
+
+	rows, cols := mock.CapturedPTYSize()
+	assert.Equal(t, rows, uint16(24))
+	assert.Equal(t, cols, uint16(80))
+	assert.Equal(t, string(mock.CapturedPTYInput()), "hunter2\r")
+}
+
+// TestMockPTYTerminal ensures that a mocked command attached to a PTY
+// behaves like it is running in a terminal.
+func TestMockPTYTerminal(t *testing.T) {
+	mock := &cmdexec.MockCommand{
+		Name: "ls",
+		Handler: func(inv cmdexec.Invocation) ([]byte, []byte, error) {
+			if inv.TTY {
+				return []byte("\x1b[34mbin\x1b[0m\nREADME.md\n"), nil, nil
+			}
+			return []byte("bin\nREADME.md\n"), nil, nil
+		},
+	}
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(mock))
+
+	out, err := cmdexec.Command("ls").Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "bin\nREADME.md\n")
+
+	cmd := cmdexec.Command("ls")
+	cmd.UsePTY(24, 80)
+	assert.Error(t, cmd.ResizePTY(30, 100), "cmdexec: command is not attached to a PTY")
+	assert.NilError(t, cmd.Start())
+	assert.NilError(t, cmd.ResizePTY(30, 100))
+
+	out, err = io.ReadAll(cmd.PTY())
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "\x1b[34mbin\x1b[0m\r\nREADME.md\r\n")
+	assert.NilError(t, cmd.Wait())
+
+	rows, cols := mock.CapturedPTYSize()
+	assert.Equal(t, rows, uint16(30))
+	assert.Equal(t, cols, uint16(100))
+}
+
+// TestMockKillGroup ensures that killing the process group of a mocked
+// command is recorded.
+func TestMockKillGroup(t *testing.T) {
+	mock := &cmdexec.MockCommand{Name: "make"}
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(mock))
+
+	cmd := cmdexec.Command("make")
+	assert.NilError(t, cmd.Start())
+	assert.Error(t, cmd.KillGroup(), "cmdexec: command was not started in a new process group")
+	assert.NilError(t, cmd.Wait())
+
+	cmd.SetNewProcessGroup(true)
+	assert.Equal(t, mock.CapturedNewProcessGroup(), true)
+	assert.NilError(t, cmd.Start())
+	assert.NilError(t, cmd.KillGroup())
+	assert.NilError(t, cmd.Wait())
+	assert.DeepEqual(t, mock.CapturedSignals(), []os.Signal{os.Kill})
+}
+
+// TestMockOnSignal ensures that mocked commands react to signals as
+// configured while they are running.
+func TestMockOnSignal(t *testing.T) {
+	mock := &cmdexec.MockCommand{
+		Name:     "server",
+		Stdout:   []byte("never written"),
+		Blocking: true,
+		OnSignal: map[os.Signal]cmdexec.SignalResponse{
+			syscall.SIGTERM: {Delay: 50 * time.Millisecond, ExitStatus: 143},
+			os.Kill:         {},
+		},
+	}
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(mock))
+
+	cmd := cmdexec.Command("server")
+	assert.NilError(t, cmd.Start())
+	assert.NilError(t, cmd.Signal(os.Interrupt))
+	assert.NilError(t, cmd.Signal(syscall.SIGTERM))
+	start := time.Now()
+	err := cmd.Wait()
+	assert.Assert(t, time.Since(start) >= 50*time.Millisecond)
+	assert.Error(t, err, "exit status 143")
+	assert.Equal(t, cmd.ExitCode(), 143)
+	assert.DeepEqual(t, mock.CapturedSignals(), []os.Signal{os.Interrupt, syscall.SIGTERM})
+
+	// SIGKILL during the graceful shutdown exits immediately.
+	var out bytes.Buffer
+	cmd.SetStdout(&out)
+	assert.NilError(t, cmd.Start())
+	assert.NilError(t, cmd.Signal(syscall.SIGTERM))
+	assert.NilError(t, cmd.Kill())
+	err = cmd.Wait()
+	assert.Error(t, err, "signal: killed")
+	assert.Equal(t, cmd.ExitCode(), -1)
+	assert.Equal(t, cmd.ProcessState().Exited, false)
+	assert.Equal(t, out.String(), "")
+	assert.DeepEqual(t, mock.CapturedSignals(), []os.Signal{syscall.SIGTERM, os.Kill})
+}
+
+// TestMockExecutorExpect ensures that commands can be registered
+// through the builder returned by Expect.
+func TestMockExecutorExpect(t *testing.T) {
+	mock := cmdexec.NewMockExecutor()
+	mock.Expect("git", "status").WithDir("/repo").ReturnsStdout("clean\n").ExitCode(0).Times(2)
+	push := mock.Expect("git", "push").ReturnsStderr("rejected\n").ExitCode(1).Command()
+	cmdexec.UseMockExecutor(t, mock)
+
+	for range 2 {
+		cmd := cmdexec.Command("git", "status")
+		cmd.SetDir("/repo")
+		out, err := cmd.Output()
+		assert.NilError(t, err)
+		assert.Equal(t, string(out), "clean\n")
+	}
+
+	err := cmdexec.Command("git", "push").Run()
+	assert.Error(t, err, "exit status 1: rejected")
+	assert.Equal(t, push.ExitStatus, 1)
+}
+
+// TestMockUnorderedArgs ensures that commands with UnorderedArgs match
+// regardless of the order of their arguments.
+func TestMockUnorderedArgs(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:          "docker",
+		Args:          []string{"run", "--rm", "--name=web", "-it", "nginx"},
+		UnorderedArgs: true,
+		Stdout:        []byte("ok"),
+	}).ErrorOnUnregistered())
+
+	out, err := cmdexec.Command("docker", "run", "-it", "--name=web", "--rm", "nginx").Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "ok")
+
+	err = cmdexec.Command("docker", "run", "-it", "--name=web", "nginx").Run()
+	assert.ErrorIs(t, err, cmdexec.ErrNotRegistered)
+}
+
+// TestMockAnyArg ensures that Any matches a single argument in its
+// position only.
+func TestMockAnyArg(t *testing.T) {
+	mock := cmdexec.NewMockExecutor(
+		&cmdexec.MockCommand{Name: "git", Args: []string{"clone", cmdexec.Any, "--depth", "1"}, Stdout: []byte("any")},
+		&cmdexec.MockCommand{Name: "git", Args: []string{"clone", "https://example.com/exact.git", "--depth", "1"}, Stdout: []byte("exact")},
+	).ErrorOnUnregistered()
+	cmdexec.UseMockExecutor(t, mock)
+
+	out, err := cmdexec.Command("git", "clone", "https://example.com/repo.git", "--depth", "1").Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "any")
+
+	out, err = cmdexec.Command("git", "clone", "https://example.com/exact.git", "--depth", "1").Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "exact")
+
+	assert.ErrorIs(t, cmdexec.Command("git", "clone", "https://example.com/repo.git", "--depth", "2").Run(), cmdexec.ErrNotRegistered)
+	assert.ErrorIs(t, cmdexec.Command("git", "clone", "--depth", "1").Run(), cmdexec.ErrNotRegistered)
+}
+
+// TestMockCaseInsensitiveNames ensures that command names can be
+// matched regardless of their case and extension, like on Windows.
+func TestMockCaseInsensitiveNames(t *testing.T) {
+	mock := cmdexec.NewMockExecutor(
+		&cmdexec.MockCommand{Name: "git", Args: []string{"status"}, Stdout: []byte("clean")},
+		&cmdexec.MockCommand{Name: "kubectl.exe", AnyArgs: true, Stdout: []byte("pods")},
+	).ErrorOnUnregistered()
+	cmdexec.UseMockExecutor(t, mock)
+
+	if runtime.GOOS != "windows" {
+		assert.ErrorIs(t, cmdexec.Command("Git.EXE", "status").Run(), cmdexec.ErrNotRegistered)
+	}
+
+	mock.CaseInsensitiveNames()
+	for _, name := range []string{"git", "git.exe", "Git.EXE"} {
+		out, err := cmdexec.Command(name, "status").Output()
+		assert.NilError(t, err)
+		assert.Equal(t, string(out), "clean")
+	}
+
+	out, err := cmdexec.Command("KUBECTL", "get", "pods").Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "pods")
+	assert.ErrorIs(t, cmdexec.Command("git", "STATUS").Run(), cmdexec.ErrNotRegistered)
+}
+
+// TestMockNotFound ensures that a mocked command can fail like an
+// executable that doesn't exist.
+func TestMockNotFound(t *testing.T) {
+	mock := cmdexec.NewMockExecutor()
+	mock.AddNotFound("terraform")
+	cmdexec.UseMockExecutor(t, mock)
+
+	_, err := cmdexec.Command("terraform", "apply").Output()
+	assert.ErrorIs(t, err, exec.ErrNotFound)
+	assert.Error(t, err, `exec: "terraform": executable file not found in $PATH`)
+
+	var execErr *exec.Error
+	assert.Assert(t, errors.As(err, &execErr))
+	assert.Equal(t, execErr.Name, "terraform")
+	assert.Equal(t, len(mock.Calls()), 0)
+}
+
+// TestMockStartFailures ensures that mocked commands can fail to start
+// with the same errors as the standard executor.
+func TestMockStartFailures(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(
+		&cmdexec.MockCommand{Name: "sh", Args: []string{"denied"}, PermissionDenied: true},
+		&cmdexec.MockCommand{Name: "sh", Args: []string{"dir"}, MissingDir: true},
+		&cmdexec.MockCommand{Name: "sh", Args: []string{"big"}, ArgListTooLong: true},
+	))
+
+	sh, err := exec.LookPath("sh")
+	assert.NilError(t, err)
+
+	err = cmdexec.Command("sh", "denied").Run()
+	assert.ErrorIs(t, err, fs.ErrPermission)
+	assert.ErrorIs(t, err, syscall.EACCES)
+	assert.Error(t, err, "fork/exec "+sh+": permission denied")
+
+	cmd := cmdexec.Command("sh", "dir")
+	cmd.SetDir("/nonexistent")
+	err = cmd.Run()
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.Error(t, err, "chdir /nonexistent: no such file or directory")
+
+	err = cmdexec.Command("sh", "big").Run()
+	assert.ErrorIs(t, err, cmdexec.ErrArgListTooLong)
+	assert.Error(t, err, "fork/exec "+sh+": argument list too long")
+}
+
+// TestMockExitErrorIncludesStderr ensures that a mocked command exiting
+// with a non-zero exit code returns an ExitError containing stderr.
+func TestMockExitErrorIncludesStderr(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:       "git",
+		Args:       []string{"status"},
+		Stderr:     []byte("fatal: not a git repository\n"),
+		ExitStatus: 128,
+	}))
+
+	cmd := cmdexec.Command("git", "status")
+	_, err := cmd.Output()
+	assert.Error(t, err, "exit status 128: fatal: not a git repository")
+
+	var exitErr *cmdexec.ExitError
+	assert.Assert(t, errors.As(err, &exitErr))
+	assert.Equal(t, exitErr.Command, cmd.String())
+	assert.Equal(t, exitErr.ExitCode(), 128)
+	assert.Equal(t, string(exitErr.Stderr), "fatal: not a git repository\n")
+}
+
+// TestMockTimeout ensures that a mocked command that takes longer than
+// its timeout fails with a deadline exceeded error, without writing its
+// output.
+func TestMockTimeout(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(
+		&cmdexec.MockCommand{Name: "sleep", Stdout: []byte("done"), Delay: time.Minute},
+		&cmdexec.MockCommand{Name: "server", Stdout: []byte("done"), Blocking: true},
+		&cmdexec.MockCommand{Name: "download", Stdout: bytes.Repeat([]byte("a"), 1000), OutputRate: 100},
+	))
+
+	for _, name := range []string{"sleep", "server", "download"} {
+		cmd := cmdexec.Command(name)
+		cmd.SetTimeout(10 * time.Millisecond)
+		start := time.Now()
+		out, err := cmd.Output()
+		assert.Assert(t, time.Since(start) < 5*time.Second, name)
+		assert.ErrorIs(t, err, context.DeadlineExceeded, name)
+		assert.Error(t, err, "cmdexec: command timed out after 10ms: context deadline exceeded", name)
+		assert.Equal(t, string(out), "", name)
+		assert.Equal(t, cmd.ExitCode(), -1, name)
+	}
+
+	cmd := cmdexec.Command("sleep")
+	cmd.SetTimeout(time.Hour)
+	cmd.(*cmdexec.MockCommand).Delay = 10 * time.Millisecond
+	assert.NilError(t, cmd.Run())
+}
+
+// TestMockContext ensures that a mocked command is canceled once its
+// context is done, like [exec.CommandContext].
+func TestMockContext(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:   "sleep",
+		Stdout: []byte("done"),
+		Delay:  time.Minute,
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	cmd := cmdexec.CommandContext(ctx, "sleep")
+	out, err := cmd.Output()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, string(out), "")
+	assert.DeepEqual(t, cmd.(*cmdexec.MockCommand).CapturedSignals(), []os.Signal{os.Kill})
+
+	// Like [exec.Cmd.Start], a command isn't started with a done context,
+	// even if another command was created since.
+	cmd = cmdexec.CommandContext(ctx, "sleep")
+	cmdexec.Command("sleep")
+	assert.ErrorIs(t, cmd.Start(), context.DeadlineExceeded)
+}
+
+// TestMockDelayFunc ensures that the delay of a mocked command can be
+// computed each time it runs.
+func TestMockDelayFunc(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:    "curl",
+		AnyArgs: true,
+		DelayFunc: func(inv cmdexec.Invocation) time.Duration {
+			if slices.Contains(inv.Args, "slow.example.com") {
+				return time.Minute
+			}
+			return 0
+		},
+	}))
+
+	cmd := cmdexec.Command("curl", "slow.example.com")
+	cmd.SetTimeout(10 * time.Millisecond)
+	assert.ErrorIs(t, cmd.Run(), context.DeadlineExceeded)
+
+	cmd = cmdexec.Command("curl", "fast.example.com")
+	cmd.SetTimeout(10 * time.Millisecond)
+	assert.NilError(t, cmd.Run())
+}
+
+// TestMockOnLine ensures that mocked output is replayed through the
+// line functions.
+func TestMockOnLine(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:   "build",
+		Stdout: []byte("step 1\nstep 2\n"),
+		Stderr: []byte("warning"),
+	}))
+
+	var stdout, stderr []string
+	cmd := cmdexec.Command("build")
+	cmd.OnStdoutLine(func(line string) { stdout = append(stdout, line) })
+	cmd.OnStderrLine(func(line string) { stderr = append(stderr, line) })
+	assert.NilError(t, cmd.Run())
+	assert.DeepEqual(t, stdout, []string{"step 1", "step 2"})
+	assert.DeepEqual(t, stderr, []string{"warning"})
+}
+
+// TestMockChunks ensures that chunks are interleaved in the combined
+// output while still being split into stdout and stderr.
+func TestMockChunks(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name: "make",
+		Chunks: []cmdexec.OutputChunk{
+			{Stream: cmdexec.StreamStdout, Data: []byte("compiling\n")},
+			{Stream: cmdexec.StreamStderr, Data: []byte("warning: unused\n")},
+			{Stream: cmdexec.StreamStdout, Data: []byte("done\n")},
+		},
+	}))
+
+	cmd := cmdexec.Command("make")
+	out, err := cmd.CombinedOutput()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "compiling\nwarning: unused\ndone\n")
+
+	out, err = cmd.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "compiling\ndone\n")
+}
+
+// TestMockCombined ensures that Combined is returned by CombinedOutput
+// as is, while Output still returns Stdout.
+func TestMockCombined(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:     "make",
+		Stdout:   []byte("compiling\ndone\n"),
+		Stderr:   []byte("warning: unused\n"),
+		Combined: []byte("compiling\nwarning: unused\ndone\n"),
+	}))
+
+	cmd := cmdexec.Command("make")
+	out, err := cmd.CombinedOutput()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "compiling\nwarning: unused\ndone\n")
+
+	out, err = cmd.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "compiling\ndone\n")
+}
+
+// TestMockCapturesCredential ensures that the credential set on a
+// command is recorded.
+func TestMockCapturesCredential(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{Name: "daemon"}))
+
+	cmd := cmdexec.Command("daemon")
+	_, _, ok := cmd.(*cmdexec.MockCommand).CapturedCredential()
+	assert.Equal(t, ok, false)
+
+	cmd.SetCredential(1000, 100)
+	uid, gid, ok := cmd.(*cmdexec.MockCommand).CapturedCredential()
+	assert.Equal(t, ok, true)
+	assert.Equal(t, uid, uint32(1000))
+	assert.Equal(t, gid, uint32(100))
+}
+
+// TestMockCapturesNice ensures that the niceness set on a command is
+// recorded.
+func TestMockCapturesNice(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{Name: "batch"}))
+
+	cmd := cmdexec.Command("batch")
+	cmd.SetNice(19)
+	level, ok := cmd.(*cmdexec.MockCommand).CapturedNice()
+	assert.Equal(t, ok, true)
+	assert.Equal(t, level, 19)
+}
+
+// TestMockCapturesRlimits ensures that the resource limits set on a
+// command are recorded.
+func TestMockCapturesRlimits(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{Name: "helper"}))
+
+	limit := cmdexec.Rlimit{Resource: cmdexec.RlimitCPU, Cur: 10, Max: 20}
+	cmd := cmdexec.Command("helper")
+	cmd.SetRlimits(limit)
+	assert.DeepEqual(t, cmd.(*cmdexec.MockCommand).CapturedRlimits(), []cmdexec.Rlimit{limit})
+}
+
+// TestMockTeeStdout ensures that mocked output is written to the tee
+// writers while still being returned.
+func TestMockTeeStdout(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:   "deploy",
+		Stdout: []byte("deployed\n"),
+		Stderr: []byte("progress\n"),
+	}))
+
+	var stdout, stderr bytes.Buffer
+	cmd := cmdexec.Command("deploy")
+	cmd.TeeStdout(&stdout)
+	cmd.TeeStderr(&stderr)
+
+	out, err := cmd.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "deployed\n")
+	assert.Equal(t, stdout.String(), "deployed\n")
+	assert.Equal(t, stderr.String(), "progress\n")
+}
+
+// TestMockRunReport ensures that a result is synthesized for mocked
+// commands.
+func TestMockRunReport(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:       "test",
+		Args:       []string{"./..."},
+		Stdout:     []byte("ok\n"),
+		Stderr:     []byte("warning\n"),
+		ExitStatus: 1,
+		Delay:      10 * time.Millisecond,
+	}))
+
+	res, err := cmdexec.Command("test", "./...").RunReport()
+	assert.ErrorContains(t, err, "exit status 1")
+	assert.DeepEqual(t, res.Args, []string{"test", "./..."})
+	assert.Equal(t, res.ExitCode, 1)
+	assert.Equal(t, string(res.Stdout), "ok\n")
+	assert.Equal(t, string(res.Stderr), "warning\n")
+	assert.Assert(t, res.Duration >= 10*time.Millisecond)
+}
+
+// TestMockDone ensures that the channel returned by Done is closed once
+// a mocked command has finished.
+func TestMockDone(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:  "sleep",
+		Delay: 10 * time.Millisecond,
+	}))
+
+	cmd := cmdexec.Command("sleep")
+	assert.Assert(t, cmd.Done() == nil)
+	assert.NilError(t, cmd.Start())
+
+	select {
+	case <-cmd.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("command did not finish")
+	}
+	assert.NilError(t, cmd.Wait())
+}
+
+// TestMockSetStdoutFile ensures that mocked output is written to the
+// files set on a command.
+func TestMockSetStdoutFile(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:   "server",
+		Stdout: []byte("listening\n"),
+		Stderr: []byte("warning\n"),
+	}))
+
+	dir := t.TempDir()
+	cmd := cmdexec.Command("server")
+	cmd.SetStdoutFile(dir+"/stdout.log", cmdexec.WriteTruncate)
+	cmd.SetStderrFile(dir+"/stderr.log", cmdexec.WriteAppend)
+	assert.NilError(t, cmd.Run())
+	assert.NilError(t, cmd.Run())
+
+	b, err := os.ReadFile(dir + "/stdout.log")
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "listening\n")
+
+	b, err = os.ReadFile(dir + "/stderr.log")
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "warning\nwarning\n")
+}
+
+// TestMockSetCancelSignal ensures that the cancellation signal is sent
+// by SimulateCancel and that Wait reports the cancellation.
+func TestMockSetCancelSignal(t *testing.T) {
+	mock := &cmdexec.MockCommand{Name: "server", ExitStatus: 143}
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(mock))
+
+	cmdexec.SetDefaultCancelSignal(syscall.SIGTERM, time.Second)
+	t.Cleanup(func() { cmdexec.SetDefaultCancelSignal(nil, 0) })
+
+	cmd := cmdexec.Command("server")
+	assert.Equal(t, mock.CapturedWaitDelay(), time.Second)
+	assert.NilError(t, cmd.Start())
+	assert.NilError(t, mock.SimulateCancel())
+
+	err := cmd.Wait()
+	assert.ErrorIs(t, err, context.Canceled)
+	var exitErr *cmdexec.ExitError
+	assert.Assert(t, errors.As(err, &exitErr))
+	assert.DeepEqual(t, mock.CapturedSignals(), []os.Signal{syscall.SIGTERM})
+}
+
+// TestMockUsage ensures that the injected resource usage is reported
+// once a mocked command has exited.
+func TestMockUsage(t *testing.T) {
+	usage := cmdexec.Usage{UserTime: time.Second, SystemTime: time.Millisecond, MaxRSS: 1 << 20}
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:          "compile",
+		ResourceUsage: usage,
+	}))
+
+	cmd := cmdexec.Command("compile")
+	assert.Assert(t, cmd.Usage() == nil)
+	assert.NilError(t, cmd.Run())
+	assert.DeepEqual(t, *cmd.Usage(), usage)
+	assert.Equal(t, cmd.ProcessState().UserTime, time.Second)
+}
+
+// TestMockSetMaxOutputBytes ensures that mocked output is truncated to
+// the limit.
+func TestMockSetMaxOutputBytes(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:       "yes",
+		Stdout:     []byte("y\ny\ny\n"),
+		ExitStatus: 1,
+	}))
+
+	cmd := cmdexec.Command("yes")
+	cmd.SetMaxOutputBytes(4)
+	out, err := cmd.Output()
+	assert.ErrorIs(t, err, cmdexec.ErrOutputTruncated)
+	var exitErr *cmdexec.ExitError
+	assert.Assert(t, errors.As(err, &exitErr))
+	assert.Equal(t, string(out), "y\ny\n")
+
+	cmd.SetMaxOutputBytes(0)
+	out, err = cmd.CombinedOutput()
+	assert.Assert(t, !errors.Is(err, cmdexec.ErrOutputTruncated))
+	assert.Equal(t, string(out), "y\ny\ny\n")
+}
+
+// TestMockArgPatterns ensures that commands can be matched using
+// wildcard argument patterns, with exact matches taking precedence.
+func TestMockArgPatterns(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(
+		&cmdexec.MockCommand{
+			Name:        "git",
+			ArgPatterns: []string{"-C", "/tmp/*", "status"},
+			Stdout:      []byte("pattern"),
+		},
+		&cmdexec.MockCommand{
+			Name:   "git",
+			Args:   []string{"-C", "/tmp/exact", "status"},
+			Stdout: []byte("exact"),
+		},
+	))
+
+	cmd := cmdexec.Command("git", "-C", "/tmp/TestFoo123/001", "status")
+	out, err := cmd.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "pattern")
+	assert.DeepEqual(t, cmd.Argv(), []string{"git", "-C", "/tmp/TestFoo123/001", "status"})
+
+	out, err = cmdexec.Command("git", "-C", "/tmp/exact", "status").Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "exact")
+
+	assert.Assert(t, cmp.Panics(func() {
+		cmdexec.Command("git", "-C", "/home/user", "status")
+	}))
+}
+
+// TestMockAddCommandPattern ensures that commands can be matched by a
+// regular expression over their command line.
+func TestMockAddCommandPattern(t *testing.T) {
+	mock := cmdexec.NewMockExecutor()
+	mock.AddCommandPattern(regexp.MustCompile(`^git checkout [0-9a-f]{40}$`), &cmdexec.MockCommand{
+		Stdout: []byte("checked out"),
+	})
+	mock.AddCommandPattern(regexp.MustCompile(`^git checkout -b .+$`), &cmdexec.MockCommand{
+		ExitStatus: 128,
+	})
+	cmdexec.UseMockExecutor(t, mock)
+
+	cmd := cmdexec.Command("git", "checkout", "0123456789abcdef0123456789abcdef01234567")
+	out, err := cmd.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "checked out")
+	assert.Equal(t, filepath.Base(cmd.Path()), "git")
+	assert.Equal(t, cmd.String(), cmd.Path()+" checkout 0123456789abcdef0123456789abcdef01234567")
+
+	cmd = cmdexec.Command("git", "checkout", "-b", "main")
+	var exitErr *cmdexec.ExitError
+	assert.Assert(t, errors.As(cmd.Run(), &exitErr))
+	assert.Equal(t, exitErr.Command, cmd.Path()+" checkout -b main")
+
+	assert.Assert(t, cmp.Panics(func() {
+		cmdexec.Command("git", "checkout", "main")
+	}))
+}
+
+// TestMockAddCommandPrefix ensures that commands can be matched by a
+// prefix of their arguments.
+func TestMockAddCommandPrefix(t *testing.T) {
+	mock := cmdexec.NewMockExecutor()
+	mock.AddCommandPrefix(&cmdexec.MockCommand{
+		Name:   "kubectl",
+		Args:   []string{"get"},
+		Stdout: []byte("pods"),
+	})
+	cmdexec.UseMockExecutor(t, mock)
+
+	cmd := cmdexec.Command("kubectl", "get", "pods", "-n", "default")
+	other := cmdexec.Command("kubectl", "get", "svc")
+	out, err := cmd.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "pods")
+	assert.DeepEqual(t, cmd.Argv(), []string{"kubectl", "get", "pods", "-n", "default"})
+	assert.DeepEqual(t, other.Argv(), []string{"kubectl", "get", "svc"})
+	assert.Equal(t, filepath.Base(other.Path()), "kubectl")
+	assert.Equal(t, other.String(), other.Path()+" get svc")
+	assert.DeepEqual(t, mock.Calls()[0].Args, []string{"get", "pods", "-n", "default"})
+
+	assert.Assert(t, cmp.Panics(func() {
+		cmdexec.Command("kubectl", "delete", "pods")
+	}))
+}
+
+// TestMockAddMatch ensures that commands can be matched by a custom
+// matcher.
+func TestMockAddMatch(t *testing.T) {
+	mock := cmdexec.NewMockExecutor()
+	mock.AddMatch(cmdexec.MatcherFunc(func(name string, args []string) bool {
+		return name == "terraform" && slices.Contains(args, "-dry-run")
+	}), &cmdexec.MockCommand{Stdout: []byte("dry run")})
+	cmdexec.UseMockExecutor(t, mock)
+
+	out, err := cmdexec.Command("terraform", "apply", "-dry-run", "-auto-approve").Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "dry run")
+
+	assert.Assert(t, cmp.Panics(func() {
+		cmdexec.Command("terraform", "apply")
+	}))
+}
+
+// TestMockAnyArgs ensures that commands with AnyArgs match regardless
+// of their arguments.
+func TestMockAnyArgs(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(
+		&cmdexec.MockCommand{Name: "docker", AnyArgs: true, Stdout: []byte("any")},
+		&cmdexec.MockCommand{Name: "docker", Args: []string{"version"}, Stdout: []byte("exact")},
+	))
+
+	for _, args := range [][]string{nil, {"run", "--rm", "alpine"}} {
+		out, err := cmdexec.Command("docker", args...).Output()
+		assert.NilError(t, err)
+		assert.Equal(t, string(out), "any")
+	}
+
+	out, err := cmdexec.Command("docker", "version").Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "exact")
+}
+
+// TestMockExecutorCalls ensures that every execution of a mocked
+// command is recorded in order.
+func TestMockExecutorCalls(t *testing.T) {
+	mock := cmdexec.NewMockExecutor(&cmdexec.MockCommand{Name: "git", AnyArgs: true})
+	cmdexec.UseMockExecutor(t, mock)
+
+	cmd := cmdexec.Command("git", "fetch")
+	cmd.SetDir("/tmp")
+	cmd.SetEnviron([]string{"GIT_DIR=.git"})
+	assert.NilError(t, cmd.Run())
+
+	cmd = cmdexec.Command("git", "apply")
+	cmd.SetStdinString("diff")
+	assert.NilError(t, cmd.Run())
+
+	calls := mock.Calls()
+	assert.Equal(t, len(calls), 2)
+	assert.Equal(t, calls[0].Name, "git")
+	assert.DeepEqual(t, calls[0].Args, []string{"fetch"})
+	assert.Equal(t, calls[0].Dir, "/tmp")
+	assert.DeepEqual(t, calls[0].Env, []string{"GIT_DIR=.git"})
+	assert.Assert(t, calls[0].Stdin == nil)
+	assert.DeepEqual(t, calls[1].Args, []string{"apply"})
+	assert.Equal(t, string(calls[1].Stdin), "diff")
+	assert.Assert(t, !calls[1].Time.Before(calls[0].Time))
+}
+
+// TestMockExecutorOnMatch ensures that OnMatch is called for every
+// execution of a mocked command as it happens.
+func TestMockExecutorOnMatch(t *testing.T) {
+	var matched []string
+	mock := cmdexec.NewMockExecutor(&cmdexec.MockCommand{Name: "git", AnyArgs: true}).
+		OnMatch(func(inv cmdexec.Invocation) {
+			matched = append(matched, inv.Dir+": "+strings.Join(inv.Args, " "))
+		})
+	cmdexec.UseMockExecutor(t, mock)
+
+	cmd := cmdexec.Command("git", "fetch")
+	cmd.SetDir("/src")
+	assert.NilError(t, cmd.Run())
+	assert.DeepEqual(t, matched, []string{"/src: fetch"})
+
+	cmd = cmdexec.Command("git", "push", "origin")
+	cmd.SetDir("/tmp")
+	assert.NilError(t, cmd.Run())
+	assert.DeepEqual(t, matched, []string{"/src: fetch", "/tmp: push origin"})
+}
+
+// TestMockCommandHandler ensures that a Handler computes the output of
+// a command from how it was called.
+func TestMockCommandHandler(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:    "tr",
+		AnyArgs: true,
+		Handler: func(inv cmdexec.Invocation) ([]byte, []byte, error) {
+			if len(inv.Args) != 2 {
+				return nil, []byte("usage: tr string1 string2"), &cmdexec.ExitError{Code: 1}
+			}
+			return bytes.ReplaceAll(inv.Stdin, []byte(inv.Args[0]), []byte(inv.Args[1])), nil, nil
+		},
+	}))
+
+	cmd := cmdexec.Command("tr", "a", "b")
+	cmd.SetStdinString("banana")
+	out, err := cmd.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "bbnbnb")
+
+	cmd = cmdexec.Command("tr")
+	out, err = cmd.CombinedOutput()
+	var exitErr *cmdexec.ExitError
+	assert.Assert(t, errors.As(err, &exitErr))
+	assert.Equal(t, exitErr.Code, 1)
+	assert.Equal(t, exitErr.Command, cmd.String())
+	assert.Equal(t, string(exitErr.Stderr), "usage: tr string1 string2")
+	assert.Equal(t, string(out), "usage: tr string1 string2")
+}
+
+// TestMockSetStdoutStderr ensures that the mocked output is written to
+// the writers provided to SetStdout and SetStderr.
+func TestMockSetStdoutStderr(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:   "make",
+		Stdout: []byte("building\n"),
+		Stderr: []byte("warning: unused variable\n"),
+	}))
+
+	var stdout, stderr bytes.Buffer
+	cmd := cmdexec.Command("make")
+	cmd.SetStdout(&stdout)
+	cmd.SetStderr(&stderr)
+	cmd.SetStdinString("y\n")
+	assert.NilError(t, cmd.Run())
+	assert.Equal(t, stdout.String(), "building\n")
+	assert.Equal(t, stderr.String(), "warning: unused variable\n")
+
+	// The writers and stdin of a command aren't used by the next one.
+	cmd = cmdexec.Command("make")
+	assert.NilError(t, cmd.Run())
+	assert.Equal(t, stdout.String(), "building\n")
+	assert.Equal(t, stderr.String(), "warning: unused variable\n")
+	assert.Assert(t, cmd.(*cmdexec.MockCommand).CapturedStdin() == nil)
+}
+
+// TestMockExecutorPassthrough ensures that commands that weren't
+// registered are executed for real with Passthrough.
+func TestMockExecutorPassthrough(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:   "terraform",
+		Args:   []string{"apply"},
+		Stdout: []byte("Apply complete!"),
+	}).Passthrough())
+
+	out, err := cmdexec.Command("terraform", "apply").Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "Apply complete!")
+
+	out, err = cmdexec.Command("go", "env", "GOOS").Output()
+	assert.NilError(t, err)
+	assert.Equal(t, strings.TrimSpace(string(out)), runtime.GOOS)
+}
+
+// TestMockExecutorErrorOnUnregistered ensures that commands that
+// weren't registered fail to start instead of panicking with
+// ErrorOnUnregistered.
+func TestMockExecutorErrorOnUnregistered(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor().ErrorOnUnregistered())
+
+	errCh := make(chan error)
+	go func() {
+		_, err := cmdexec.Command("rm", "-rf", "/").Output()
+		errCh <- err
+	}()
+
+	err := <-errCh
+	assert.ErrorIs(t, err, cmdexec.ErrNotRegistered)
+	assert.Error(t, err, "cmdexec: no command registered for 'rm -rf /' missing call to MockExecutor.AddCommand?")
+}
+
+// TestMockExecutorSetDefault ensures that the default command is used
+// for every command that wasn't registered.
+func TestMockExecutorSetDefault(t *testing.T) {
+	mock := cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:       "make",
+		Args:       []string{"deploy"},
+		ExitStatus: 1,
+	})
+	mock.SetDefault(&cmdexec.MockCommand{Stdout: []byte("ok")})
+	cmdexec.UseMockExecutor(t, mock)
+
+	assert.Error(t, cmdexec.Command("make", "deploy").Run(), "exit status 1")
+
+	cmd := cmdexec.Command("make", "build")
+	out, err := cmd.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "ok")
+	assert.DeepEqual(t, cmd.Argv(), []string{"make", "build"})
+	assert.Equal(t, filepath.Base(cmd.Path()), "make")
+	assert.Equal(t, cmd.String(), cmd.Path()+" build")
+
+	mock.SetDefault(&cmdexec.MockCommand{ExitStatus: 2})
+	cmd = cmdexec.Command("make", "test")
+	var exitErr *cmdexec.ExitError
+	assert.Assert(t, errors.As(cmd.Run(), &exitErr))
+	assert.Equal(t, exitErr.Command, cmd.Path()+" test")
+}
+
+// TestMockExecutorSetDefaultFor ensures that a per-name default is used
+// for unregistered arguments of that name, but not for other names.
+func TestMockExecutorSetDefaultFor(t *testing.T) {
+	mock := cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:   "git",
+		Args:   []string{"push"},
+		Stdout: []byte("pushed"),
+	})
+	mock.SetDefaultFor("git", &cmdexec.MockCommand{Stdout: []byte("/repo\n")})
+	mock.SetDefault(&cmdexec.MockCommand{ExitStatus: 1})
+	cmdexec.UseMockExecutor(t, mock)
+
+	out, err := cmdexec.Command("git", "push").Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "pushed")
+
+	cmd := cmdexec.Command("git", "rev-parse", "--show-toplevel")
+	out, err = cmd.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "/repo\n")
+	assert.DeepEqual(t, cmd.Argv(), []string{"git", "rev-parse", "--show-toplevel"})
+
+	assert.Error(t, cmdexec.Command("make").Run(), "exit status 1")
+}
+
+// TestMockExecutorConcurrentAddCommand ensures that commands can be
+// registered while others are being executed.
+func TestMockExecutorConcurrentAddCommand(t *testing.T) {
+	mock := cmdexec.NewMockExecutor()
+	cmdexec.UseMockExecutor(t, mock)
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			arg := strconv.Itoa(i)
+			mock.AddCommand(&cmdexec.MockCommand{Name: "echo", Args: []string{arg}, Stdout: []byte(arg)})
+			out, err := cmdexec.Command("echo", arg).Output()
+			assert.Check(t, err)
+			assert.Check(t, cmp.Equal(string(out), arg))
+		}()
+	}
+	wg.Wait()
+}
+
+// TestMockConcurrentInvocations ensures that a registered command can
+// be run concurrently and again afterwards, as every command created
+// from it is a separate invocation.
+func TestMockConcurrentInvocations(t *testing.T) {
+	mock := &cmdexec.MockCommand{Name: "git", Args: []string{"status"}, Stdout: []byte("clean"), Delay: 10 * time.Millisecond}
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(mock.Times(5)))
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			out, err := cmdexec.Command("git", "status").Output()
+			assert.Check(t, err)
+			assert.Check(t, cmp.Equal(string(out), "clean"))
+		}()
+	}
+	wg.Wait()
+
+	out, err := cmdexec.Command("git", "status").Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "clean")
+}
+
+// TestMockExecutorRemoveCommand ensures that commands can be removed
+// from an executor that is in use.
+func TestMockExecutorRemoveCommand(t *testing.T) {
+	mock := cmdexec.NewMockExecutor(
+		&cmdexec.MockCommand{Name: "git", Args: []string{"push"}},
+		&cmdexec.MockCommand{Name: "git", AnyArgs: true, ExitStatus: 1},
+	).ErrorOnUnregistered()
+	cmdexec.UseMockExecutor(t, mock)
+
+	assert.NilError(t, cmdexec.Command("git", "push").Run())
+	assert.Equal(t, mock.RemoveCommand("git", "push"), true)
+	assert.Error(t, cmdexec.Command("git", "push").Run(), "exit status 1")
+
+	assert.Equal(t, mock.RemoveCommand("git"), true)
+	assert.Equal(t, mock.RemoveCommand("git"), false)
+	assert.ErrorIs(t, cmdexec.Command("git", "push").Run(), cmdexec.ErrNotRegistered)
+}
+
+// TestMockExecutorReset ensures that all commands and recorded calls
+// are removed by Reset.
+func TestMockExecutorReset(t *testing.T) {
+	mock := cmdexec.NewMockExecutor(&cmdexec.MockCommand{Name: "ls"}).ErrorOnUnregistered()
+	cmdexec.UseMockExecutor(t, mock)
+
+	assert.NilError(t, cmdexec.Command("ls").Run())
+	mock.Reset()
+	assert.Equal(t, len(mock.Calls()), 0)
+	assert.ErrorIs(t, cmdexec.Command("ls").Run(), cmdexec.ErrNotRegistered)
+
+	mock.AddCommand(&cmdexec.MockCommand{Name: "ls"})
+	assert.NilError(t, cmdexec.Command("ls").Run())
+	assert.Equal(t, len(mock.Calls()), 1)
+}
+
+// TestMockBlocking ensures that a Blocking mocked command runs until it
+// is completed.
+func TestMockBlocking(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:     "server",
+		Blocking: true,
+	}))
+
+	cmd := cmdexec.Command("server")
+	assert.Error(t, cmd.(*cmdexec.MockCommand).Complete(nil), "cmdexec: command is not running or not Blocking")
+	assert.NilError(t, cmd.Start())
+
+	select {
+	case <-cmd.Done():
+		t.Fatal("command finished before being completed")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	assert.NilError(t, cmd.(*cmdexec.MockCommand).Complete(io.ErrUnexpectedEOF))
+	assert.Error(t, cmd.(*cmdexec.MockCommand).Complete(nil), "cmdexec: command was already completed")
+	assert.ErrorIs(t, cmd.Wait(), io.ErrUnexpectedEOF)
+}
+
+// TestMockChunkAfter ensures that chunks are written progressively
+// once their delay has elapsed.
+func TestMockChunkAfter(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name: "download",
+		Chunks: []cmdexec.OutputChunk{
+			{Stream: cmdexec.StreamStdout, Data: []byte("10%\n")},
+			{Stream: cmdexec.StreamStdout, Data: []byte("100%\n"), After: 50 * time.Millisecond},
+		},
+	}))
+
+	var lines []string
+	var times []time.Time
+	cmd := cmdexec.Command("download")
+	cmd.OnStdoutLine(func(line string) {
+		lines = append(lines, line)
+		times = append(times, time.Now())
+	})
+	assert.NilError(t, cmd.Run())
+
+	assert.DeepEqual(t, lines, []string{"10%", "100%"})
+	assert.Assert(t, times[1].Sub(times[0]) >= 50*time.Millisecond)
+}
+
+// TestMockOutputRate ensures that output is written no faster than
+// OutputRate, so that a stalled command can be detected and killed.
+func TestMockOutputRate(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:       "download",
+		Stdout:     []byte(strings.Repeat("x", 30)),
+		OutputRate: 100,
+	}))
+
+	start := time.Now()
+	out, err := cmdexec.Command("download").Output()
+	assert.NilError(t, err)
+	assert.Equal(t, len(out), 30)
+	assert.Assert(t, time.Since(start) >= 300*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+
+	var buf bytes.Buffer
+	cmd := cmdexec.CommandContext(ctx, "download")
+	cmd.SetStdout(&buf)
+	assert.ErrorIs(t, cmd.Run(), context.DeadlineExceeded)
+	assert.Equal(t, buf.String(), strings.Repeat("x", 10))
+}
+
+// TestMockFailAfter ensures that a command can fail after writing only
+// part of its output, which is what a streaming reader sees.
+func TestMockFailAfter(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:      "kubectl",
+		Args:      []string{"logs", "-f", "web-0"},
+		Stdout:    []byte("line 1\nline 2\nline 3\n"),
+		FailAfter: 10,
+	}))
+
+	cmd := cmdexec.Command("kubectl", "logs", "-f", "web-0")
+	stdout, err := cmd.StdoutPipe()
+	assert.NilError(t, err)
+	assert.NilError(t, cmd.Start())
+
+	var lines []string
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	assert.NilError(t, scanner.Err())
+	assert.DeepEqual(t, lines, []string{"line 1", "lin"})
+
+	var exitErr *cmdexec.ExitError
+	assert.Assert(t, errors.As(cmd.Wait(), &exitErr))
+	assert.Equal(t, exitErr.Code, 141)
+
+	out, err := cmdexec.Command("kubectl", "logs", "-f", "web-0").Output()
+	assert.ErrorContains(t, err, "exit status 141")
+	assert.Equal(t, string(out), "line 1\nlin")
+}
+
+// TestMockScript ensures that a mocked command can have an interactive
+// conversation through its stdin and stdout.
+func TestMockScript(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name: "login",
+		Script: []cmdexec.ScriptStep{
+			{Respond: []byte("Username: ")},
+			{Expect: "admin", Respond: []byte("Password: ")},
+			{Expect: "hunter2", Respond: []byte("\nLogged in!\n")},
+		},
+	}))
+
+	cmd := cmdexec.Command("login")
+	stdin, err := cmd.StdinPipe()
+	assert.NilError(t, err)
+	stdout, err := cmd.StdoutPipe()
+	assert.NilError(t, err)
+	assert.NilError(t, cmd.Start())
+
+	r := bufio.NewReader(stdout)
+	for _, answer := range []string{"admin", "hunter2"} {
+		prompt, err := r.ReadString(' ')
+		assert.NilError(t, err)
+		assert.Assert(t, strings.HasSuffix(prompt, ": "))
+
+		_, err = io.WriteString(stdin, answer+"\n")
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, stdin.Close())
+
+	rest, err := io.ReadAll(r)
+	assert.NilError(t, err)
+	assert.Equal(t, string(rest), "\nLogged in!\n")
+	assert.NilError(t, cmd.Wait())
+
+	cmd.SetStdinString("root\n")
+	_, err = cmd.Output()
+	assert.Error(t, err, `expected stdin line "admin" for script step 1 but got "root"`)
+}
+
+// BenchmarkMockExecutorLookup measures looking up a registered command
+// among many others.
+func BenchmarkMockExecutorLookup(b *testing.B) {
+	mock := cmdexec.NewMockExecutor()
+	for i := range 1000 {
+		mock.AddCommand(&cmdexec.MockCommand{Name: "kubectl", Args: []string{"get", "pod", "web-" + strconv.Itoa(i), "-o", "json"}})
+	}
+	e := mock.Executor(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		e.Command("kubectl", "get", "pod", "web-500", "-o", "json")
+	}
+}
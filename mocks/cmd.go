@@ -0,0 +1,760 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ../cmdexec.go
+//
+// Generated by this command:
+//
+//	mockgen -source=../cmdexec.go -destination=cmd.go -package=mocks -write_package_comment=false
+//
+
+package mocks
+
+import (
+	io "io"
+	os "os"
+	reflect "reflect"
+	syscall "syscall"
+	time "time"
+
+	cmdexec "github.com/jaredallard/cmdexec"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockCmd is a mock of Cmd interface.
+type MockCmd struct {
+	ctrl     *gomock.Controller
+	recorder *MockCmdMockRecorder
+	isgomock struct{}
+}
+
+// MockCmdMockRecorder is the mock recorder for MockCmd.
+type MockCmdMockRecorder struct {
+	mock *MockCmd
+}
+
+// NewMockCmd creates a new mock instance.
+func NewMockCmd(ctrl *gomock.Controller) *MockCmd {
+	mock := &MockCmd{ctrl: ctrl}
+	mock.recorder = &MockCmdMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCmd) EXPECT() *MockCmdMockRecorder {
+	return m.recorder
+}
+
+// AppendEnv mocks base method.
+func (m *MockCmd) AppendEnv(kv ...string) {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range kv {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "AppendEnv", varargs...)
+}
+
+// AppendEnv indicates an expected call of AppendEnv.
+func (mr *MockCmdMockRecorder) AppendEnv(kv ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppendEnv", reflect.TypeOf((*MockCmd)(nil).AppendEnv), kv...)
+}
+
+// Argv mocks base method.
+func (m *MockCmd) Argv() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Argv")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// Argv indicates an expected call of Argv.
+func (mr *MockCmdMockRecorder) Argv() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Argv", reflect.TypeOf((*MockCmd)(nil).Argv))
+}
+
+// Clone mocks base method.
+func (m *MockCmd) Clone() cmdexec.Cmd {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Clone")
+	ret0, _ := ret[0].(cmdexec.Cmd)
+	return ret0
+}
+
+// Clone indicates an expected call of Clone.
+func (mr *MockCmdMockRecorder) Clone() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Clone", reflect.TypeOf((*MockCmd)(nil).Clone))
+}
+
+// CombinedOutput mocks base method.
+func (m *MockCmd) CombinedOutput() ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CombinedOutput")
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CombinedOutput indicates an expected call of CombinedOutput.
+func (mr *MockCmdMockRecorder) CombinedOutput() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CombinedOutput", reflect.TypeOf((*MockCmd)(nil).CombinedOutput))
+}
+
+// Done mocks base method.
+func (m *MockCmd) Done() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Done")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// Done indicates an expected call of Done.
+func (mr *MockCmdMockRecorder) Done() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Done", reflect.TypeOf((*MockCmd)(nil).Done))
+}
+
+// Environ mocks base method.
+func (m *MockCmd) Environ() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Environ")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// Environ indicates an expected call of Environ.
+func (mr *MockCmdMockRecorder) Environ() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Environ", reflect.TypeOf((*MockCmd)(nil).Environ))
+}
+
+// ExitCode mocks base method.
+func (m *MockCmd) ExitCode() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExitCode")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// ExitCode indicates an expected call of ExitCode.
+func (mr *MockCmdMockRecorder) ExitCode() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExitCode", reflect.TypeOf((*MockCmd)(nil).ExitCode))
+}
+
+// Kill mocks base method.
+func (m *MockCmd) Kill() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Kill")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Kill indicates an expected call of Kill.
+func (mr *MockCmdMockRecorder) Kill() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Kill", reflect.TypeOf((*MockCmd)(nil).Kill))
+}
+
+// KillGroup mocks base method.
+func (m *MockCmd) KillGroup() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KillGroup")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// KillGroup indicates an expected call of KillGroup.
+func (mr *MockCmdMockRecorder) KillGroup() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KillGroup", reflect.TypeOf((*MockCmd)(nil).KillGroup))
+}
+
+// KillTree mocks base method.
+func (m *MockCmd) KillTree() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KillTree")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// KillTree indicates an expected call of KillTree.
+func (mr *MockCmdMockRecorder) KillTree() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KillTree", reflect.TypeOf((*MockCmd)(nil).KillTree))
+}
+
+// OnStderrLine mocks base method.
+func (m *MockCmd) OnStderrLine(arg0 func(string)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnStderrLine", arg0)
+}
+
+// OnStderrLine indicates an expected call of OnStderrLine.
+func (mr *MockCmdMockRecorder) OnStderrLine(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnStderrLine", reflect.TypeOf((*MockCmd)(nil).OnStderrLine), arg0)
+}
+
+// OnStdoutLine mocks base method.
+func (m *MockCmd) OnStdoutLine(arg0 func(string)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnStdoutLine", arg0)
+}
+
+// OnStdoutLine indicates an expected call of OnStdoutLine.
+func (mr *MockCmdMockRecorder) OnStdoutLine(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnStdoutLine", reflect.TypeOf((*MockCmd)(nil).OnStdoutLine), arg0)
+}
+
+// Output mocks base method.
+func (m *MockCmd) Output() ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Output")
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Output indicates an expected call of Output.
+func (mr *MockCmdMockRecorder) Output() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Output", reflect.TypeOf((*MockCmd)(nil).Output))
+}
+
+// PID mocks base method.
+func (m *MockCmd) PID() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PID")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// PID indicates an expected call of PID.
+func (mr *MockCmdMockRecorder) PID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PID", reflect.TypeOf((*MockCmd)(nil).PID))
+}
+
+// PTY mocks base method.
+func (m *MockCmd) PTY() io.ReadWriter {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PTY")
+	ret0, _ := ret[0].(io.ReadWriter)
+	return ret0
+}
+
+// PTY indicates an expected call of PTY.
+func (mr *MockCmdMockRecorder) PTY() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PTY", reflect.TypeOf((*MockCmd)(nil).PTY))
+}
+
+// Path mocks base method.
+func (m *MockCmd) Path() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Path")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Path indicates an expected call of Path.
+func (mr *MockCmdMockRecorder) Path() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Path", reflect.TypeOf((*MockCmd)(nil).Path))
+}
+
+// ProcessState mocks base method.
+func (m *MockCmd) ProcessState() *cmdexec.ProcessState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProcessState")
+	ret0, _ := ret[0].(*cmdexec.ProcessState)
+	return ret0
+}
+
+// ProcessState indicates an expected call of ProcessState.
+func (mr *MockCmdMockRecorder) ProcessState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessState", reflect.TypeOf((*MockCmd)(nil).ProcessState))
+}
+
+// ResizePTY mocks base method.
+func (m *MockCmd) ResizePTY(rows, cols uint16) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResizePTY", rows, cols)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResizePTY indicates an expected call of ResizePTY.
+func (mr *MockCmdMockRecorder) ResizePTY(rows, cols any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResizePTY", reflect.TypeOf((*MockCmd)(nil).ResizePTY), rows, cols)
+}
+
+// Run mocks base method.
+func (m *MockCmd) Run() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Run")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockCmdMockRecorder) Run() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockCmd)(nil).Run))
+}
+
+// RunReport mocks base method.
+func (m *MockCmd) RunReport() (*cmdexec.RunResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunReport")
+	ret0, _ := ret[0].(*cmdexec.RunResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunReport indicates an expected call of RunReport.
+func (mr *MockCmdMockRecorder) RunReport() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunReport", reflect.TypeOf((*MockCmd)(nil).RunReport))
+}
+
+// ScrubEnv mocks base method.
+func (m *MockCmd) ScrubEnv(patterns ...string) {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range patterns {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "ScrubEnv", varargs...)
+}
+
+// ScrubEnv indicates an expected call of ScrubEnv.
+func (mr *MockCmdMockRecorder) ScrubEnv(patterns ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScrubEnv", reflect.TypeOf((*MockCmd)(nil).ScrubEnv), patterns...)
+}
+
+// SetCancel mocks base method.
+func (m *MockCmd) SetCancel(arg0 func() error) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetCancel", arg0)
+}
+
+// SetCancel indicates an expected call of SetCancel.
+func (mr *MockCmdMockRecorder) SetCancel(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCancel", reflect.TypeOf((*MockCmd)(nil).SetCancel), arg0)
+}
+
+// SetCancelSignal mocks base method.
+func (m *MockCmd) SetCancelSignal(sig os.Signal, killAfter time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetCancelSignal", sig, killAfter)
+}
+
+// SetCancelSignal indicates an expected call of SetCancelSignal.
+func (mr *MockCmdMockRecorder) SetCancelSignal(sig, killAfter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCancelSignal", reflect.TypeOf((*MockCmd)(nil).SetCancelSignal), sig, killAfter)
+}
+
+// SetCredential mocks base method.
+func (m *MockCmd) SetCredential(uid, gid uint32) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetCredential", uid, gid)
+}
+
+// SetCredential indicates an expected call of SetCredential.
+func (mr *MockCmdMockRecorder) SetCredential(uid, gid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCredential", reflect.TypeOf((*MockCmd)(nil).SetCredential), uid, gid)
+}
+
+// SetDir mocks base method.
+func (m *MockCmd) SetDir(arg0 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetDir", arg0)
+}
+
+// SetDir indicates an expected call of SetDir.
+func (mr *MockCmdMockRecorder) SetDir(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDir", reflect.TypeOf((*MockCmd)(nil).SetDir), arg0)
+}
+
+// SetEnv mocks base method.
+func (m *MockCmd) SetEnv(key, value string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetEnv", key, value)
+}
+
+// SetEnv indicates an expected call of SetEnv.
+func (mr *MockCmdMockRecorder) SetEnv(key, value any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetEnv", reflect.TypeOf((*MockCmd)(nil).SetEnv), key, value)
+}
+
+// SetEnviron mocks base method.
+func (m *MockCmd) SetEnviron(arg0 []string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetEnviron", arg0)
+}
+
+// SetEnviron indicates an expected call of SetEnviron.
+func (mr *MockCmdMockRecorder) SetEnviron(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetEnviron", reflect.TypeOf((*MockCmd)(nil).SetEnviron), arg0)
+}
+
+// SetExtraFiles mocks base method.
+func (m *MockCmd) SetExtraFiles(arg0 []*os.File) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetExtraFiles", arg0)
+}
+
+// SetExtraFiles indicates an expected call of SetExtraFiles.
+func (mr *MockCmdMockRecorder) SetExtraFiles(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetExtraFiles", reflect.TypeOf((*MockCmd)(nil).SetExtraFiles), arg0)
+}
+
+// SetMaxOutputBytes mocks base method.
+func (m *MockCmd) SetMaxOutputBytes(n int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxOutputBytes", n)
+}
+
+// SetMaxOutputBytes indicates an expected call of SetMaxOutputBytes.
+func (mr *MockCmdMockRecorder) SetMaxOutputBytes(n any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxOutputBytes", reflect.TypeOf((*MockCmd)(nil).SetMaxOutputBytes), n)
+}
+
+// SetNewProcessGroup mocks base method.
+func (m *MockCmd) SetNewProcessGroup(arg0 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetNewProcessGroup", arg0)
+}
+
+// SetNewProcessGroup indicates an expected call of SetNewProcessGroup.
+func (mr *MockCmdMockRecorder) SetNewProcessGroup(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetNewProcessGroup", reflect.TypeOf((*MockCmd)(nil).SetNewProcessGroup), arg0)
+}
+
+// SetNice mocks base method.
+func (m *MockCmd) SetNice(level int) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetNice", level)
+}
+
+// SetNice indicates an expected call of SetNice.
+func (mr *MockCmdMockRecorder) SetNice(level any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetNice", reflect.TypeOf((*MockCmd)(nil).SetNice), level)
+}
+
+// SetRlimits mocks base method.
+func (m *MockCmd) SetRlimits(limits ...cmdexec.Rlimit) {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range limits {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "SetRlimits", varargs...)
+}
+
+// SetRlimits indicates an expected call of SetRlimits.
+func (mr *MockCmdMockRecorder) SetRlimits(limits ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRlimits", reflect.TypeOf((*MockCmd)(nil).SetRlimits), limits...)
+}
+
+// SetStderr mocks base method.
+func (m *MockCmd) SetStderr(arg0 io.Writer) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetStderr", arg0)
+}
+
+// SetStderr indicates an expected call of SetStderr.
+func (mr *MockCmdMockRecorder) SetStderr(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStderr", reflect.TypeOf((*MockCmd)(nil).SetStderr), arg0)
+}
+
+// SetStderrFile mocks base method.
+func (m *MockCmd) SetStderrFile(path string, mode cmdexec.WriteMode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetStderrFile", path, mode)
+}
+
+// SetStderrFile indicates an expected call of SetStderrFile.
+func (mr *MockCmdMockRecorder) SetStderrFile(path, mode any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStderrFile", reflect.TypeOf((*MockCmd)(nil).SetStderrFile), path, mode)
+}
+
+// SetStdin mocks base method.
+func (m *MockCmd) SetStdin(arg0 io.Reader) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetStdin", arg0)
+}
+
+// SetStdin indicates an expected call of SetStdin.
+func (mr *MockCmdMockRecorder) SetStdin(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStdin", reflect.TypeOf((*MockCmd)(nil).SetStdin), arg0)
+}
+
+// SetStdinBytes mocks base method.
+func (m *MockCmd) SetStdinBytes(arg0 []byte) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetStdinBytes", arg0)
+}
+
+// SetStdinBytes indicates an expected call of SetStdinBytes.
+func (mr *MockCmdMockRecorder) SetStdinBytes(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStdinBytes", reflect.TypeOf((*MockCmd)(nil).SetStdinBytes), arg0)
+}
+
+// SetStdinString mocks base method.
+func (m *MockCmd) SetStdinString(arg0 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetStdinString", arg0)
+}
+
+// SetStdinString indicates an expected call of SetStdinString.
+func (mr *MockCmdMockRecorder) SetStdinString(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStdinString", reflect.TypeOf((*MockCmd)(nil).SetStdinString), arg0)
+}
+
+// SetStdout mocks base method.
+func (m *MockCmd) SetStdout(arg0 io.Writer) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetStdout", arg0)
+}
+
+// SetStdout indicates an expected call of SetStdout.
+func (mr *MockCmdMockRecorder) SetStdout(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStdout", reflect.TypeOf((*MockCmd)(nil).SetStdout), arg0)
+}
+
+// SetStdoutFile mocks base method.
+func (m *MockCmd) SetStdoutFile(path string, mode cmdexec.WriteMode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetStdoutFile", path, mode)
+}
+
+// SetStdoutFile indicates an expected call of SetStdoutFile.
+func (mr *MockCmdMockRecorder) SetStdoutFile(path, mode any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStdoutFile", reflect.TypeOf((*MockCmd)(nil).SetStdoutFile), path, mode)
+}
+
+// SetSysProcAttr mocks base method.
+func (m *MockCmd) SetSysProcAttr(arg0 *syscall.SysProcAttr) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSysProcAttr", arg0)
+}
+
+// SetSysProcAttr indicates an expected call of SetSysProcAttr.
+func (mr *MockCmdMockRecorder) SetSysProcAttr(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSysProcAttr", reflect.TypeOf((*MockCmd)(nil).SetSysProcAttr), arg0)
+}
+
+// SetTimeout mocks base method.
+func (m *MockCmd) SetTimeout(arg0 time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetTimeout", arg0)
+}
+
+// SetTimeout indicates an expected call of SetTimeout.
+func (mr *MockCmdMockRecorder) SetTimeout(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTimeout", reflect.TypeOf((*MockCmd)(nil).SetTimeout), arg0)
+}
+
+// SetWaitDelay mocks base method.
+func (m *MockCmd) SetWaitDelay(arg0 time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetWaitDelay", arg0)
+}
+
+// SetWaitDelay indicates an expected call of SetWaitDelay.
+func (mr *MockCmdMockRecorder) SetWaitDelay(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWaitDelay", reflect.TypeOf((*MockCmd)(nil).SetWaitDelay), arg0)
+}
+
+// Signal mocks base method.
+func (m *MockCmd) Signal(arg0 os.Signal) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Signal", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Signal indicates an expected call of Signal.
+func (mr *MockCmdMockRecorder) Signal(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Signal", reflect.TypeOf((*MockCmd)(nil).Signal), arg0)
+}
+
+// Start mocks base method.
+func (m *MockCmd) Start() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Start")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Start indicates an expected call of Start.
+func (mr *MockCmdMockRecorder) Start() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockCmd)(nil).Start))
+}
+
+// StderrPipe mocks base method.
+func (m *MockCmd) StderrPipe() (io.ReadCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StderrPipe")
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StderrPipe indicates an expected call of StderrPipe.
+func (mr *MockCmdMockRecorder) StderrPipe() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StderrPipe", reflect.TypeOf((*MockCmd)(nil).StderrPipe))
+}
+
+// StdinPipe mocks base method.
+func (m *MockCmd) StdinPipe() (io.WriteCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StdinPipe")
+	ret0, _ := ret[0].(io.WriteCloser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StdinPipe indicates an expected call of StdinPipe.
+func (mr *MockCmdMockRecorder) StdinPipe() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StdinPipe", reflect.TypeOf((*MockCmd)(nil).StdinPipe))
+}
+
+// StdoutPipe mocks base method.
+func (m *MockCmd) StdoutPipe() (io.ReadCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StdoutPipe")
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StdoutPipe indicates an expected call of StdoutPipe.
+func (mr *MockCmdMockRecorder) StdoutPipe() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StdoutPipe", reflect.TypeOf((*MockCmd)(nil).StdoutPipe))
+}
+
+// String mocks base method.
+func (m *MockCmd) String() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "String")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// String indicates an expected call of String.
+func (mr *MockCmdMockRecorder) String() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "String", reflect.TypeOf((*MockCmd)(nil).String))
+}
+
+// TeeStderr mocks base method.
+func (m *MockCmd) TeeStderr(arg0 io.Writer) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "TeeStderr", arg0)
+}
+
+// TeeStderr indicates an expected call of TeeStderr.
+func (mr *MockCmdMockRecorder) TeeStderr(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TeeStderr", reflect.TypeOf((*MockCmd)(nil).TeeStderr), arg0)
+}
+
+// TeeStdout mocks base method.
+func (m *MockCmd) TeeStdout(arg0 io.Writer) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "TeeStdout", arg0)
+}
+
+// TeeStdout indicates an expected call of TeeStdout.
+func (mr *MockCmdMockRecorder) TeeStdout(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TeeStdout", reflect.TypeOf((*MockCmd)(nil).TeeStdout), arg0)
+}
+
+// Usage mocks base method.
+func (m *MockCmd) Usage() *cmdexec.Usage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Usage")
+	ret0, _ := ret[0].(*cmdexec.Usage)
+	return ret0
+}
+
+// Usage indicates an expected call of Usage.
+func (mr *MockCmdMockRecorder) Usage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Usage", reflect.TypeOf((*MockCmd)(nil).Usage))
+}
+
+// UseOSStreams mocks base method.
+func (m *MockCmd) UseOSStreams(stdin bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UseOSStreams", stdin)
+}
+
+// UseOSStreams indicates an expected call of UseOSStreams.
+func (mr *MockCmdMockRecorder) UseOSStreams(stdin any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseOSStreams", reflect.TypeOf((*MockCmd)(nil).UseOSStreams), stdin)
+}
+
+// UsePTY mocks base method.
+func (m *MockCmd) UsePTY(rows, cols uint16) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UsePTY", rows, cols)
+}
+
+// UsePTY indicates an expected call of UsePTY.
+func (mr *MockCmdMockRecorder) UsePTY(rows, cols any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsePTY", reflect.TypeOf((*MockCmd)(nil).UsePTY), rows, cols)
+}
+
+// Wait mocks base method.
+func (m *MockCmd) Wait() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Wait")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Wait indicates an expected call of Wait.
+func (mr *MockCmdMockRecorder) Wait() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Wait", reflect.TypeOf((*MockCmd)(nil).Wait))
+}
@@ -0,0 +1,94 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ../executor.go
+//
+// Generated by this command:
+//
+//	mockgen -source=../executor.go -destination=commander.go -package=mocks -write_package_comment=false
+//
+
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	cmdexec "github.com/jaredallard/cmdexec"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockCommander is a mock of Commander interface.
+type MockCommander struct {
+	ctrl     *gomock.Controller
+	recorder *MockCommanderMockRecorder
+	isgomock struct{}
+}
+
+// MockCommanderMockRecorder is the mock recorder for MockCommander.
+type MockCommanderMockRecorder struct {
+	mock *MockCommander
+}
+
+// NewMockCommander creates a new mock instance.
+func NewMockCommander(ctrl *gomock.Controller) *MockCommander {
+	mock := &MockCommander{ctrl: ctrl}
+	mock.recorder = &MockCommanderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCommander) EXPECT() *MockCommanderMockRecorder {
+	return m.recorder
+}
+
+// Command mocks base method.
+func (m *MockCommander) Command(name string, arg ...string) cmdexec.Cmd {
+	m.ctrl.T.Helper()
+	varargs := []any{name}
+	for _, a := range arg {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Command", varargs...)
+	ret0, _ := ret[0].(cmdexec.Cmd)
+	return ret0
+}
+
+// Command indicates an expected call of Command.
+func (mr *MockCommanderMockRecorder) Command(name any, arg ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{name}, arg...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Command", reflect.TypeOf((*MockCommander)(nil).Command), varargs...)
+}
+
+// CommandContext mocks base method.
+func (m *MockCommander) CommandContext(ctx context.Context, name string, arg ...string) cmdexec.Cmd {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, name}
+	for _, a := range arg {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CommandContext", varargs...)
+	ret0, _ := ret[0].(cmdexec.Cmd)
+	return ret0
+}
+
+// CommandContext indicates an expected call of CommandContext.
+func (mr *MockCommanderMockRecorder) CommandContext(ctx, name any, arg ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, name}, arg...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommandContext", reflect.TypeOf((*MockCommander)(nil).CommandContext), varargs...)
+}
+
+// CommandString mocks base method.
+func (m *MockCommander) CommandString(ctx context.Context, cmdline string) (cmdexec.Cmd, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CommandString", ctx, cmdline)
+	ret0, _ := ret[0].(cmdexec.Cmd)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CommandString indicates an expected call of CommandString.
+func (mr *MockCommanderMockRecorder) CommandString(ctx, cmdline any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommandString", reflect.TypeOf((*MockCommander)(nil).CommandString), ctx, cmdline)
+}
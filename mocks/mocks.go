@@ -0,0 +1,35 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+// Package mocks contains mocks of the interfaces of cmdexec generated
+// by [mockgen], for use with [gomock]. They are regenerated whenever
+// the interfaces change, so they don't need to be generated by
+// consumers.
+//
+// [mockgen]: https://github.com/uber-go/mock
+// [gomock]: https://pkg.go.dev/go.uber.org/mock/gomock
+package mocks
+
+//go:generate go run go.uber.org/mock/mockgen@v0.5.2 -source=../cmdexec.go -destination=cmd.go -package=mocks -write_package_comment=false
+//go:generate go run go.uber.org/mock/mockgen@v0.5.2 -source=../executor.go -destination=commander.go -package=mocks -write_package_comment=false
+
+import "github.com/jaredallard/cmdexec"
+
+// Ensure that the mocks are regenerated when the interfaces change.
+var (
+	_ cmdexec.Cmd       = (*MockCmd)(nil)
+	_ cmdexec.Commander = (*MockCommander)(nil)
+)
@@ -0,0 +1,29 @@
+package mocks_test
+
+import (
+	"testing"
+
+	"github.com/jaredallard/cmdexec"
+	"github.com/jaredallard/cmdexec/mocks"
+	"go.uber.org/mock/gomock"
+	"gotest.tools/v3/assert"
+)
+
+// TestMocks ensures that the generated mocks can stand in for an
+// Executor and the commands it creates.
+func TestMocks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	cmd := mocks.NewMockCmd(ctrl)
+	cmd.EXPECT().Output().Return([]byte("clean\n"), nil)
+
+	commander := mocks.NewMockCommander(ctrl)
+	commander.EXPECT().Command("git", "status").Return(cmd)
+
+	status := func(c cmdexec.Commander) string {
+		out, err := c.Command("git", "status").Output()
+		assert.NilError(t, err)
+		return string(out)
+	}
+	assert.Equal(t, status(commander), "clean\n")
+}
@@ -0,0 +1,99 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"strings"
+
+	"github.com/jaredallard/cmdexec/internal/mockt"
+)
+
+// orderConstraint is a command that must have run before another one,
+// see [MockCommand.After] and [MockExecutor.InOrder].
+type orderConstraint struct {
+	cmd *MockCommand
+
+	// strict denotes if every run of cmd must have happened before,
+	// instead of only its first one.
+	strict bool
+}
+
+// After requires the command to only run once each of cmds has run,
+// while commands without constraints may run at any time. Later runs of
+// cmds are allowed, e.g., "git push" after "git commit" is satisfied by
+// commit, push, commit. The order is verified once the test that called
+// [UseMockExecutor] has finished, and only if the command ran at all.
+// It returns the command to allow chaining.
+func (c *MockCommand) After(cmds ...*MockCommand) *MockCommand {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	for _, cmd := range cmds {
+		c.after = append(c.after, orderConstraint{cmd: cmd})
+	}
+	return c
+}
+
+// InOrder requires cmds to run in the order provided, where each
+// command may only run once the previous one has run, and the previous
+// one may not run again afterwards.
+// Unlike [MockCommand.After], interleaving is not allowed, e.g., "init",
+// "plan", "init" fails for InOrder(init, plan). Other commands may still
+// run at any time. It returns the executor to allow chaining.
+func (e *MockExecutor) InOrder(cmds ...*MockCommand) *MockExecutor {
+	for i := 1; i < len(cmds); i++ {
+		cmds[i].statsMu.Lock()
+		cmds[i].after = append(cmds[i].after, orderConstraint{cmd: cmds[i-1], strict: true})
+		cmds[i].statsMu.Unlock()
+	}
+	return e
+}
+
+// runSeqs returns the sequence numbers of the first and last run of the
+// command.
+func (c *MockCommand) runSeqs() (first, last uint64) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	return c.firstRun, c.lastRun
+}
+
+// verifyOrder reports an error to t if the command ran before the
+// commands it must run after, see After and InOrder.
+func (c *MockCommand) verifyOrder(t mockt.T) {
+	c.statsMu.Lock()
+	after, first := c.after, c.firstRun
+	c.statsMu.Unlock()
+
+	if first == 0 {
+		return
+	}
+
+	argv := strings.Join(c.Argv(), " ")
+	for _, o := range after {
+		depFirst, depLast := o.cmd.runSeqs()
+		depArgv := strings.Join(o.cmd.Argv(), " ")
+		switch {
+		case depFirst == 0:
+			t.Errorf("cmdexec: expected '%s' to run after '%s', but '%s' never ran", argv, depArgv, depArgv)
+		case o.strict && depLast > first:
+			t.Errorf("cmdexec: expected every run of '%s' to be before '%s', but it ran after it", depArgv, argv)
+		case !o.strict && depFirst > first:
+			t.Errorf("cmdexec: expected '%s' to run after '%s', but it ran before it", argv, depArgv)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"iter"
+)
+
+// maxErrorOutputLen is the maximum number of bytes of output included
+// in errors returned by helpers that parse the output of a command.
+const maxErrorOutputLen = 1024
+
+// OutputLines starts cmd and returns an iterator over the lines it
+// writes to stdout, without buffering the entire output in memory.
+// Lines are split using [bufio.ScanLines], so line endings are not
+// included. If reading the output fails or the command exits with an
+// error, the error is yielded as the last element.
+//
+// If iteration is stopped early, the command is killed.
+//
+// Usage:
+//
+//	for line, err := range cmdexec.OutputLines(cmdexec.Command("git", "log")) {
+//	    if err != nil {
+//	        return err
+//	    }
+//
+//	    // Do something with line.
+//	}
+func OutputLines(cmd Cmd) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			yield("", err)
+			return
+		}
+
+		if err := cmd.Start(); err != nil {
+			yield("", err)
+			return
+		}
+
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			if !yield(scanner.Text(), nil) {
+				cmd.Kill() //nolint:errcheck // Why: Best effort.
+				cmd.Wait() //nolint:errcheck // Why: We're no longer interested in the result.
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			cmd.Kill() //nolint:errcheck // Why: Best effort.
+			cmd.Wait() //nolint:errcheck // Why: Reading failed, which takes precedence.
+			yield("", err)
+			return
+		}
+
+		if err := cmd.Wait(); err != nil {
+			yield("", err)
+		}
+	}
+}
+
+// OutputJSON runs cmd and decodes its stdout as JSON into a value of
+// type T. If decoding fails, the returned error includes the output of
+// the command (truncated, if large) to aid in debugging.
+//
+// Usage:
+//
+//	pods, err := cmdexec.OutputJSON[PodList](cmdexec.Command("kubectl", "get", "pods", "-o", "json"))
+func OutputJSON[T any](cmd Cmd) (T, error) {
+	var v T
+
+	out, err := cmd.Output()
+	if err != nil {
+		return v, err
+	}
+
+	if err := json.Unmarshal(out, &v); err != nil {
+		if len(out) > maxErrorOutputLen {
+			out = append(out[:maxErrorOutputLen:maxErrorOutputLen], "..."...)
+		}
+		return v, fmt.Errorf("failed to decode output of %q as JSON: %w (output: %q)", cmd.String(), err, out)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,115 @@
+package cmdexec_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/jaredallard/cmdexec"
+	"gotest.tools/v3/assert"
+)
+
+// TestOutputLines ensures that the lines written to stdout by a command
+// can be iterated over.
+func TestOutputLines(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:       "git",
+		Args:       []string{"log", "--oneline"},
+		Stdout:     []byte("abc123 first\r\ndef456 second\nghi789 third"),
+		ExitStatus: 1,
+	}))
+
+	var lines []string
+	var errs []error
+	for line, err := range cmdexec.OutputLines(cmdexec.Command("git", "log", "--oneline")) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	assert.DeepEqual(t, lines, []string{"abc123 first", "def456 second", "ghi789 third"})
+	assert.Equal(t, len(errs), 1)
+	assert.Error(t, errs[0], "exit status 1")
+}
+
+// TestOutputLinesStopsEarly ensures that a command is killed when
+// iteration is stopped early.
+func TestOutputLinesStopsEarly(t *testing.T) {
+	mock := &cmdexec.MockCommand{
+		Name:   "yes",
+		Stdout: []byte("y\ny\ny\n"),
+	}
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(mock))
+
+	for line, err := range cmdexec.OutputLines(cmdexec.Command("yes")) {
+		assert.NilError(t, err)
+		assert.Equal(t, line, "y")
+		break
+	}
+
+	assert.DeepEqual(t, mock.CapturedSignals(), []os.Signal{os.Kill})
+}
+
+// TestOutputJSON ensures that the output of a command can be decoded as
+// JSON, and that decode errors include the output.
+func TestOutputJSON(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:   "docker",
+		Args:   []string{"inspect", "valid"},
+		Stdout: []byte(`[{"Id": "abc123"}]`),
+	}, &cmdexec.MockCommand{
+		Name:   "docker",
+		Args:   []string{"inspect", "invalid"},
+		Stdout: []byte(`Error: not JSON`),
+	}))
+
+	type container struct {
+		ID string `json:"Id"`
+	}
+
+	containers, err := cmdexec.OutputJSON[[]container](cmdexec.Command("docker", "inspect", "valid"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, containers, []container{{ID: "abc123"}})
+
+	cmd := cmdexec.Command("docker", "inspect", "invalid")
+	_, err = cmdexec.OutputJSON[[]container](cmd)
+	assert.ErrorContains(t, err, fmt.Sprintf("failed to decode output of %q as JSON", cmd.String()))
+	assert.ErrorContains(t, err, `(output: "Error: not JSON")`)
+}
+
+// TestOutputSpill ensures that output above the threshold is spilled
+// to a temporary file that is removed once closed.
+func TestOutputSpill(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(
+		&cmdexec.MockCommand{Name: "small", Stdout: []byte("hello")},
+		&cmdexec.MockCommand{Name: "large", Stdout: bytes.Repeat([]byte("a"), 1024)},
+	))
+
+	dir := t.TempDir()
+
+	out, err := cmdexec.OutputSpill(cmdexec.Command("small"), 512, dir)
+	assert.NilError(t, err)
+	b, err := io.ReadAll(out)
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "hello")
+	assert.NilError(t, out.Close())
+
+	out, err = cmdexec.OutputSpill(cmdexec.Command("large"), 512, dir)
+	assert.NilError(t, err)
+	files, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(files), 1)
+
+	b, err = io.ReadAll(out)
+	assert.NilError(t, err)
+	assert.Equal(t, len(b), 1024)
+	assert.NilError(t, out.Close())
+
+	files, err = os.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(files), 0)
+}
@@ -0,0 +1,122 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// bufferedPipe is an in-memory pipe used by [MockCommand] to emulate
+// the pipes returned by [exec.Cmd]. Unlike [io.Pipe], writes never
+// block; data is buffered until it is read. Reads block until data is
+// available or the write side of the pipe has been closed.
+type bufferedPipe struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	buf  bytes.Buffer
+
+	// wclosed and rclosed denote if the write and read side of the pipe
+	// have been closed respectively.
+	wclosed bool
+	rclosed bool
+}
+
+// newBufferedPipe creates a new, empty, [bufferedPipe].
+func newBufferedPipe() *bufferedPipe {
+	p := &bufferedPipe{}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// Read reads from the pipe, blocking until data is available or the
+// write side of the pipe has been closed, at which point [io.EOF] is
+// returned.
+func (p *bufferedPipe) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for p.buf.Len() == 0 && !p.wclosed && !p.rclosed {
+		p.cond.Wait()
+	}
+
+	if p.rclosed {
+		return 0, io.ErrClosedPipe
+	}
+
+	if p.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+
+	return p.buf.Read(b)
+}
+
+// Write writes to the pipe. If either side of the pipe has been closed,
+// [io.ErrClosedPipe] is returned.
+func (p *bufferedPipe) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.wclosed || p.rclosed {
+		return 0, io.ErrClosedPipe
+	}
+
+	defer p.cond.Broadcast()
+	return p.buf.Write(b)
+}
+
+// CloseWrite closes the write side of the pipe. Readers will receive
+// [io.EOF] once all buffered data has been read.
+func (p *bufferedPipe) CloseWrite() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.wclosed = true
+	p.cond.Broadcast()
+	return nil
+}
+
+// CloseRead closes the read side of the pipe, discarding any buffered
+// data. Further writes return [io.ErrClosedPipe].
+func (p *bufferedPipe) CloseRead() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rclosed = true
+	p.buf.Reset()
+	p.cond.Broadcast()
+	return nil
+}
+
+// pipeReader is the read side of a [bufferedPipe].
+type pipeReader struct{ p *bufferedPipe }
+
+// Read implements [io.Reader].
+func (r *pipeReader) Read(b []byte) (int, error) { return r.p.Read(b) }
+
+// Close implements [io.Closer].
+func (r *pipeReader) Close() error { return r.p.CloseRead() }
+
+// pipeWriter is the write side of a [bufferedPipe].
+type pipeWriter struct{ p *bufferedPipe }
+
+// Write implements [io.Writer].
+func (w *pipeWriter) Write(b []byte) (int, error) { return w.p.Write(b) }
+
+// Close implements [io.Closer].
+func (w *pipeWriter) Close() error { return w.p.CloseWrite() }
@@ -0,0 +1,56 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+//go:build !unix && !windows
+
+package cmdexec
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// setCredential returns an error, as running a process as another user
+// through a uid and gid is not supported on this platform.
+func setCredential(_ *syscall.SysProcAttr, _, _ uint32) error {
+	return errors.New("cmdexec: credentials are not supported on this platform")
+}
+
+// setNice returns an error, as process priorities are not supported on
+// this platform.
+func setNice(_ *os.Process, _ int) error {
+	return errors.New("cmdexec: process priorities are not supported on this platform")
+}
+
+// processGroup is the process group a command is started in when using
+// [Cmd.SetNewProcessGroup]. This platform has no process groups, so it
+// only refers to the process itself.
+type processGroup struct{}
+
+// configure is a no-op on platforms without process groups.
+func (*processGroup) configure(_ *syscall.SysProcAttr) {}
+
+// started is a no-op on platforms without process groups.
+func (*processGroup) started(_ *os.Process) {}
+
+// kill kills p, as this platform has no process groups.
+func (*processGroup) kill(p *os.Process) error {
+	return p.Kill()
+}
+
+// release is a no-op on platforms without process groups.
+func (*processGroup) release() {}
@@ -0,0 +1,64 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+//go:build unix
+
+package cmdexec
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// setCredential configures attr to run the process as the provided
+// user and group.
+func setCredential(attr *syscall.SysProcAttr, uid, gid uint32) error {
+	attr.Credential = &syscall.Credential{Uid: uid, Gid: gid}
+	return nil
+}
+
+// setNice sets the niceness of the running process p.
+func setNice(p *os.Process, nice int) error {
+	return os.NewSyscallError("setpriority", syscall.Setpriority(syscall.PRIO_PROCESS, p.Pid, nice))
+}
+
+// processGroup is the process group a command is started in when using
+// [Cmd.SetNewProcessGroup].
+type processGroup struct{}
+
+// configure configures attr to start the process in a new process
+// group.
+func (*processGroup) configure(attr *syscall.SysProcAttr) {
+	attr.Setpgid = true
+	attr.Pgid = 0
+}
+
+// started is called once the process has been started. The process is
+// already the leader of its process group, so this is a no-op.
+func (*processGroup) started(_ *os.Process) {}
+
+// kill kills the process group led by p.
+func (*processGroup) kill(p *os.Process) error {
+	err := syscall.Kill(-p.Pid, syscall.SIGKILL)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
+
+// release releases any resources associated with the process group.
+func (*processGroup) release() {}
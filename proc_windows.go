@@ -0,0 +1,148 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Access rights required to assign a process to a Job Object, see
+// https://learn.microsoft.com/en-us/windows/win32/procthread/process-security-and-access-rights
+const (
+	processTerminate      = 0x0001
+	processSetQuota       = 0x0100
+	processSetInformation = 0x0200
+)
+
+// Priority classes, see
+// https://learn.microsoft.com/en-us/windows/win32/api/processthreadsapi/nf-processthreadsapi-setpriorityclass
+const (
+	idlePriorityClass        = 0x0040
+	belowNormalPriorityClass = 0x4000
+	normalPriorityClass      = 0x0020
+	aboveNormalPriorityClass = 0x8000
+	highPriorityClass        = 0x0080
+)
+
+// Job Object and priority functions, which aren't provided by the
+// syscall package.
+var (
+	kernel32                     = syscall.NewLazyDLL("kernel32.dll")
+	procCreateJobObjectW         = kernel32.NewProc("CreateJobObjectW")
+	procAssignProcessToJobObject = kernel32.NewProc("AssignProcessToJobObject")
+	procTerminateJobObject       = kernel32.NewProc("TerminateJobObject")
+	procSetPriorityClass         = kernel32.NewProc("SetPriorityClass")
+)
+
+// setCredential returns an error, as running a process as another user
+// through a uid and gid is not supported on this platform.
+func setCredential(_ *syscall.SysProcAttr, _, _ uint32) error {
+	return errors.New("cmdexec: credentials are not supported on this platform")
+}
+
+// setNice sets the priority class of the running process p to the one
+// closest to the provided Unix niceness.
+func setNice(p *os.Process, nice int) error {
+	class := normalPriorityClass
+	switch {
+	case nice >= 15:
+		class = idlePriorityClass
+	case nice >= 5:
+		class = belowNormalPriorityClass
+	case nice <= -15:
+		class = highPriorityClass
+	case nice <= -5:
+		class = aboveNormalPriorityClass
+	}
+
+	proc, err := syscall.OpenProcess(processSetInformation, false, uint32(p.Pid))
+	if err != nil {
+		return os.NewSyscallError("OpenProcess", err)
+	}
+	defer syscall.CloseHandle(proc) //nolint:errcheck // Why: Best effort.
+
+	if ok, _, err := procSetPriorityClass.Call(uintptr(proc), uintptr(class)); ok == 0 {
+		return os.NewSyscallError("SetPriorityClass", err)
+	}
+	return nil
+}
+
+// processGroup is the process group a command is started in when using
+// [Cmd.SetNewProcessGroup]. Windows has no way to terminate a process
+// group, so the process is also assigned to a Job Object, which is
+// terminated instead. Child processes spawned by the process are
+// automatically placed into the same Job Object.
+type processGroup struct {
+	// job is the Job Object the process was assigned to, or zero if
+	// creating or assigning it failed.
+	job syscall.Handle
+}
+
+// configure configures attr to start the process in a new process
+// group.
+func (*processGroup) configure(attr *syscall.SysProcAttr) {
+	attr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+// started assigns the started process to a new Job Object. This is
+// best effort: if it fails, only the process itself is killed by kill.
+// Children spawned before the process is assigned are not part of the
+// Job Object.
+func (g *processGroup) started(p *os.Process) {
+	job, _, _ := procCreateJobObjectW.Call(0, 0)
+	if job == 0 {
+		return
+	}
+
+	proc, err := syscall.OpenProcess(processTerminate|processSetQuota, false, uint32(p.Pid))
+	if err != nil {
+		syscall.CloseHandle(syscall.Handle(job)) //nolint:errcheck // Why: Best effort.
+		return
+	}
+	defer syscall.CloseHandle(proc) //nolint:errcheck // Why: Best effort.
+
+	if ok, _, _ := procAssignProcessToJobObject.Call(job, uintptr(proc)); ok == 0 {
+		syscall.CloseHandle(syscall.Handle(job)) //nolint:errcheck // Why: Best effort.
+		return
+	}
+
+	g.job = syscall.Handle(job)
+}
+
+// kill terminates the Job Object the process was assigned to, killing
+// it and all of its children. If the process couldn't be assigned to
+// a Job Object, only the process itself is killed.
+func (g *processGroup) kill(p *os.Process) error {
+	if g.job == 0 {
+		return p.Kill()
+	}
+
+	if ok, _, err := procTerminateJobObject.Call(uintptr(g.job), 1); ok == 0 {
+		return os.NewSyscallError("TerminateJobObject", err)
+	}
+	return nil
+}
+
+// release closes the handle to the Job Object, if one was created.
+func (g *processGroup) release() {
+	if g.job != 0 {
+		syscall.CloseHandle(g.job) //nolint:errcheck // Why: Best effort.
+		g.job = 0
+	}
+}
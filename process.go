@@ -0,0 +1,78 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// errNoProcessGroup is returned when attempting to kill the process
+// group of a command that wasn't started in its own process group.
+var errNoProcessGroup = errors.New("cmdexec: command was not started in a new process group")
+
+// errNoPTY is returned when attempting to resize the PTY of a command
+// that isn't attached to one.
+var errNoPTY = errors.New("cmdexec: command is not attached to a PTY")
+
+// ProcessState contains information about an exited process. It
+// mirrors the information provided by [os.ProcessState], but, unlike
+// it, can be constructed by mocks.
+type ProcessState struct {
+	// Pid is the process ID of the exited process.
+	Pid int
+
+	// ExitCode is the exit code of the exited process, or -1 if the
+	// process was terminated by a signal. See
+	// [os.ProcessState.ExitCode].
+	ExitCode int
+
+	// Exited reports whether the program has exited. On Unix systems
+	// this reports true if the program exited due to calling exit, but
+	// false if the program terminated due to a signal.
+	Exited bool
+
+	// Success reports whether the program exited successfully, such as
+	// with exit status 0 on Unix.
+	Success bool
+
+	// SystemTime is the system CPU time of the exited process and its
+	// children.
+	SystemTime time.Duration
+
+	// UserTime is the user CPU time of the exited process and its
+	// children.
+	UserTime time.Duration
+}
+
+// newProcessState creates a [ProcessState] from the provided
+// [os.ProcessState]. If ps is nil, nil is returned.
+func newProcessState(ps *os.ProcessState) *ProcessState {
+	if ps == nil {
+		return nil
+	}
+
+	return &ProcessState{
+		Pid:        ps.Pid(),
+		ExitCode:   ps.ExitCode(),
+		Exited:     ps.Exited(),
+		Success:    ps.Success(),
+		SystemTime: ps.SystemTime(),
+		UserTime:   ps.UserTime(),
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+//go:build unix || windows
+
+package cmdexec
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/creack/pty"
+)
+
+// startPTY starts cmd attached to a new PTY of the given size,
+// returning the controlling side of the PTY.
+func startPTY(cmd *exec.Cmd, rows, cols uint16) (*os.File, error) {
+	return pty.StartWithSize(cmd, &pty.Winsize{Rows: rows, Cols: cols})
+}
+
+// resizePTY changes the size of the PTY f to the given size.
+func resizePTY(f *os.File, rows, cols uint16) error {
+	return pty.Setsize(f, &pty.Winsize{Rows: rows, Cols: cols})
+}
@@ -0,0 +1,37 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+//go:build !unix && !windows
+
+package cmdexec
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// startPTY returns an error, as PTYs are not supported on this
+// platform.
+func startPTY(_ *exec.Cmd, _, _ uint16) (*os.File, error) {
+	return nil, errors.New("cmdexec: PTYs are not supported on this platform")
+}
+
+// resizePTY returns an error, as PTYs are not supported on this
+// platform.
+func resizePTY(_ *os.File, _, _ uint16) error {
+	return errors.New("cmdexec: PTYs are not supported on this platform")
+}
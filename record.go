@@ -0,0 +1,319 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jaredallard/cmdexec/internal/mockt"
+)
+
+// RecordingExecutor executes commands for real while recording their
+// input and output, allowing mocks to be generated from a real run. The
+// recorded commands are written as a txtar archive that can be loaded
+// with [LoadTxtar], see [UseRecordingExecutor].
+//
+// Output read through StdoutPipe or StderrPipe isn't recorded.
+type RecordingExecutor struct {
+	// envKeys are the environment variables to record.
+	envKeys []string
+
+	// mu protects calls.
+	mu    sync.Mutex
+	calls []recordedCall
+}
+
+// recordedCall is a single execution of a command recorded by a
+// [RecordingExecutor].
+type recordedCall struct {
+	argv     []string
+	env      []string
+	stdin    []byte
+	stdout   []byte
+	stderr   []byte
+	exitCode int
+}
+
+// NewRecordingExecutor returns a new [RecordingExecutor]. The values of
+// the environment variables named by envKeys are recorded, if set, and
+// expected once replayed (see [MockCommand.ExpectedEnv]).
+func NewRecordingExecutor(envKeys ...string) *RecordingExecutor {
+	return &RecordingExecutor{envKeys: envKeys}
+}
+
+// UseRecordingExecutor replaces the executor used by cmdexec with rec
+// until the test has finished, at which point the recorded commands are
+// written to path, e.g.:
+//
+//	func TestSomething(t *testing.T) {
+//	    cmdexec.UseRecordingExecutor(t, cmdexec.NewRecordingExecutor(), "testdata/something.txtar")
+//
+//	    // Your test code here.
+//	}
+//
+// Like [UseMockExecutor], this function can only be called once per
+// test.
+func UseRecordingExecutor(t mockt.T, rec *RecordingExecutor, path string) {
+	useExecutor(t, "UseRecordingExecutor", rec.executor, func() {
+		if err := rec.writeFile(path); err != nil {
+			t.Errorf("cmdexec: failed to write recorded commands: %v", err)
+		}
+	})
+}
+
+// WriteTxtar writes the recorded commands to w as a txtar archive, see
+// [ParseTxtar].
+func (r *RecordingExecutor) WriteTxtar(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b bytes.Buffer
+	for _, call := range r.calls {
+		for _, arg := range call.argv {
+			if strings.ContainsAny(arg, "\r\n") {
+				return fmt.Errorf("cmdexec: can't record %q: arguments contain a newline", call.argv)
+			}
+		}
+
+		cmdline := joinCommandLine(call.argv)
+		if len(call.env) > 0 {
+			writeTxtarFile(&b, "env", cmdline, []byte(strings.Join(call.env, "\n")+"\n"))
+		}
+		writeTxtarFile(&b, "stdin", cmdline, call.stdin)
+		writeTxtarFile(&b, "stdout", cmdline, call.stdout)
+		writeTxtarFile(&b, "stderr", cmdline, call.stderr)
+		writeTxtarFile(&b, "exit", cmdline, []byte(strconv.Itoa(call.exitCode)+"\n"))
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// writeFile writes the recorded commands to path, see WriteTxtar.
+func (r *RecordingExecutor) writeFile(path string) error {
+	var b bytes.Buffer
+	if err := r.WriteTxtar(&b); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b.Bytes(), 0o644)
+}
+
+// writeTxtarFile writes a file containing data for the stream of the
+// command line to b, if data isn't empty.
+func writeTxtarFile(b *bytes.Buffer, stream, cmdline string, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		stream += txtarNoNewline
+		data = append(data, '\n')
+	}
+	fmt.Fprintf(b, "-- %s: %s --\n", stream, cmdline)
+	b.Write(data)
+}
+
+// executor implements the [executorFn] type, returning a Cmd that
+// executes the command for real and records it once it has finished.
+func (r *RecordingExecutor) executor(ctx context.Context, name string, arg ...string) Cmd {
+	return &recordingCmd{Cmd: stdExecutor(ctx, name, arg...), rec: r}
+}
+
+// record records the execution of cmd.
+func (r *RecordingExecutor) record(cmd *recordingCmd) {
+	call := recordedCall{
+		argv:     cmd.Argv(),
+		stdout:   cmd.stdout.Bytes(),
+		stderr:   cmd.stderr.Bytes(),
+		exitCode: cmd.ExitCode(),
+	}
+	if cmd.stdinBytes != nil {
+		call.stdin = cmd.stdinBytes
+	} else if cmd.stdin != nil {
+		call.stdin = cmd.stdin.Bytes()
+	}
+
+	env := cmd.Environ()
+	for _, key := range r.envKeys {
+		// Like [os.Getenv], the last value of a variable wins.
+		for i := len(env) - 1; i >= 0; i-- {
+			if k, _, _ := strings.Cut(env[i], "="); k == key {
+				call.env = append(call.env, env[i])
+				break
+			}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, call)
+}
+
+// recordingCmd is a [Cmd] created by a [RecordingExecutor], which
+// records its input and output while it is executed.
+type recordingCmd struct {
+	Cmd
+
+	// rec is the executor the command is recorded by.
+	rec *RecordingExecutor
+
+	// stdout and stderr contain the output of the command, which is
+	// written to them through TeeStdout and TeeStderr respectively,
+	// alongside teeStdout and teeStderr set by the caller.
+	stdout, stderr       bytes.Buffer
+	teeStdout, teeStderr io.Writer
+
+	// stdinBytes contains the stdin set by SetStdinString or
+	// SetStdinBytes. Otherwise, stdin contains the stdin read by the
+	// command, if provided.
+	stdinBytes []byte
+	stdin      *bytes.Buffer
+}
+
+// tee ensures the output of the command is written to the recorded
+// stdout and stderr. This must be called before the command is started.
+func (c *recordingCmd) tee() {
+	c.Cmd.TeeStdout(multiWriter(c.teeStdout, &c.stdout))
+	c.Cmd.TeeStderr(multiWriter(c.teeStderr, &c.stderr))
+}
+
+// Start implements [Cmd.Start].
+func (c *recordingCmd) Start() error {
+	c.tee()
+	return c.Cmd.Start()
+}
+
+// Run implements [Cmd.Run].
+func (c *recordingCmd) Run() error {
+	c.tee()
+	err := c.Cmd.Run()
+	c.rec.record(c)
+	return err
+}
+
+// Wait implements [Cmd.Wait].
+func (c *recordingCmd) Wait() error {
+	err := c.Cmd.Wait()
+	c.rec.record(c)
+	return err
+}
+
+// Output implements [Cmd.Output].
+func (c *recordingCmd) Output() ([]byte, error) {
+	c.tee()
+	out, err := c.Cmd.Output()
+	c.rec.record(c)
+	return out, err
+}
+
+// CombinedOutput implements [Cmd.CombinedOutput].
+func (c *recordingCmd) CombinedOutput() ([]byte, error) {
+	c.tee()
+	out, err := c.Cmd.CombinedOutput()
+	c.rec.record(c)
+	return out, err
+}
+
+// RunReport implements [Cmd.RunReport].
+func (c *recordingCmd) RunReport() (*RunResult, error) {
+	c.tee()
+	res, err := c.Cmd.RunReport()
+	c.rec.record(c)
+	return res, err
+}
+
+// Clone implements [Cmd.Clone].
+func (c *recordingCmd) Clone() Cmd {
+	return &recordingCmd{
+		Cmd:        c.Cmd.Clone(),
+		rec:        c.rec,
+		teeStdout:  c.teeStdout,
+		teeStderr:  c.teeStderr,
+		stdinBytes: c.stdinBytes,
+	}
+}
+
+// TeeStdout implements [Cmd.TeeStdout].
+func (c *recordingCmd) TeeStdout(w io.Writer) {
+	c.teeStdout = w
+}
+
+// TeeStderr implements [Cmd.TeeStderr].
+func (c *recordingCmd) TeeStderr(w io.Writer) {
+	c.teeStderr = w
+}
+
+// SetStdin implements [Cmd.SetStdin].
+func (c *recordingCmd) SetStdin(r io.Reader) {
+	c.stdinBytes, c.stdin = nil, nil
+	if r != nil {
+		c.stdin = new(bytes.Buffer)
+		r = io.TeeReader(r, c.stdin)
+	}
+	c.Cmd.SetStdin(r)
+}
+
+// SetStdinString implements [Cmd.SetStdinString].
+func (c *recordingCmd) SetStdinString(s string) {
+	c.SetStdinBytes([]byte(s))
+}
+
+// SetStdinBytes implements [Cmd.SetStdinBytes].
+func (c *recordingCmd) SetStdinBytes(b []byte) {
+	c.stdinBytes, c.stdin = append([]byte{}, b...), nil
+	c.Cmd.SetStdinBytes(b)
+}
+
+// StdinPipe implements [Cmd.StdinPipe].
+func (c *recordingCmd) StdinPipe() (io.WriteCloser, error) {
+	w, err := c.Cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	c.stdinBytes, c.stdin = nil, new(bytes.Buffer)
+	return &teeWriteCloser{w, c.stdin}, nil
+}
+
+// UseOSStreams implements [Cmd.UseOSStreams].
+func (c *recordingCmd) UseOSStreams(stdin bool) {
+	c.Cmd.UseOSStreams(stdin)
+	if stdin {
+		c.SetStdin(os.Stdin)
+	}
+}
+
+// teeWriteCloser is an [io.WriteCloser] that also writes to tee.
+type teeWriteCloser struct {
+	io.WriteCloser
+	tee io.Writer
+}
+
+// Write implements [io.Writer].
+func (w *teeWriteCloser) Write(b []byte) (int, error) {
+	n, err := w.WriteCloser.Write(b)
+	w.tee.Write(b[:n]) //nolint:errcheck // Why: Writing to a buffer never fails.
+	return n, err
+}
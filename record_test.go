@@ -0,0 +1,50 @@
+//go:build !windows
+
+package cmdexec_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jaredallard/cmdexec"
+	"gotest.tools/v3/assert"
+)
+
+// TestRecordingExecutor ensures that commands recorded by the
+// RecordingExecutor can be replayed by a MockExecutor.
+func TestRecordingExecutor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recorded.txtar")
+	t.Setenv("RECORDED", "yes")
+
+	run := func(t *testing.T) (string, string, error) {
+		out, err := cmdexec.Command("echo", "hello world").Output()
+		assert.NilError(t, err)
+
+		cmd := cmdexec.Command("cat")
+		cmd.SetStdinString("no newline")
+		in, err := cmd.Output()
+		assert.NilError(t, err)
+
+		_, err = cmdexec.Command("sh", "-c", "echo 'it failed' >&2; exit 3").Output()
+		return string(out), string(in), err
+	}
+
+	t.Run("record", func(t *testing.T) {
+		cmdexec.UseRecordingExecutor(t, cmdexec.NewRecordingExecutor("RECORDED"), path)
+
+		out, in, err := run(t)
+		assert.Equal(t, out, "hello world\n")
+		assert.Equal(t, in, "no newline")
+		assert.Error(t, err, "exit status 3: it failed")
+	})
+
+	cmds, err := cmdexec.LoadTxtar(path)
+	assert.NilError(t, err)
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(cmds...))
+
+	out, in, err := run(t)
+	assert.Equal(t, out, "hello world\n")
+	assert.Equal(t, in, "no newline")
+	assert.Error(t, err, "exit status 3: it failed")
+	assert.DeepEqual(t, cmds[0].ExpectedEnv, []string{"RECORDED=yes"})
+}
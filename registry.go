@@ -0,0 +1,115 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// registry tracks the running commands when enabled through
+// [TrackCommands].
+var registry struct {
+	mu      sync.Mutex
+	enabled bool
+	cmds    map[Cmd]struct{}
+}
+
+// TrackCommands enables (or disables) tracking of all commands started
+// through cmdexec, so that the ones still running can be terminated
+// using [ShutdownAll], e.g., on program exit or in test teardown.
+// Tracking is disabled by default. Disabling it only stops tracking
+// commands started afterwards.
+//
+// Usage:
+//
+//	func TestMain(m *testing.M) {
+//	    cmdexec.TrackCommands(true)
+//	    code := m.Run()
+//	    cmdexec.ShutdownAll(context.Background())
+//	    os.Exit(code)
+//	}
+func TrackCommands(enabled bool) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.enabled = enabled
+}
+
+// ShutdownAll terminates all tracked commands that are still running
+// (see [TrackCommands]). SIGTERM is sent to each command and, if they
+// haven't exited by the time ctx is done, they are killed along with
+// their descendants (see [Cmd.KillTree]). ShutdownAll waits for the
+// commands to exit, so [Cmd.Wait] returns immediately for them
+// afterwards.
+func ShutdownAll(ctx context.Context) error {
+	registry.mu.Lock()
+	cmds := make([]Cmd, 0, len(registry.cmds))
+	for cmd := range registry.cmds {
+		cmds = append(cmds, cmd)
+	}
+	registry.mu.Unlock()
+
+	var errs []error
+	for _, cmd := range cmds {
+		if err := signalOrKill(cmd, syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, cmd := range cmds {
+		select {
+		case <-cmd.Done():
+			continue
+		case <-ctx.Done():
+		}
+
+		if err := cmd.KillTree(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			errs = append(errs, err)
+		}
+		<-cmd.Done()
+	}
+
+	return errors.Join(errs...)
+}
+
+// track adds cmd to the registry, if enabled. It must be called once
+// cmd has been started.
+func track(cmd Cmd) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if !registry.enabled {
+		return
+	}
+
+	if registry.cmds == nil {
+		registry.cmds = make(map[Cmd]struct{})
+	}
+	registry.cmds[cmd] = struct{}{}
+}
+
+// untrack removes cmd from the registry once it has exited.
+func untrack(cmd Cmd) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	delete(registry.cmds, cmd)
+}
@@ -0,0 +1,38 @@
+package cmdexec_test
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jaredallard/cmdexec"
+	"gotest.tools/v3/assert"
+)
+
+// TestShutdownAll ensures that tracked commands that are still running
+// are terminated.
+func TestShutdownAll(t *testing.T) {
+	mock := &cmdexec.MockCommand{Name: "server", Delay: 50 * time.Millisecond}
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(mock))
+
+	cmdexec.TrackCommands(true)
+	t.Cleanup(func() { cmdexec.TrackCommands(false) })
+
+	cmd := cmdexec.Command("server")
+	assert.NilError(t, cmd.Start())
+	assert.NilError(t, cmdexec.ShutdownAll(context.Background()))
+
+	select {
+	case <-cmd.Done():
+	default:
+		t.Fatal("expected command to have exited")
+	}
+	assert.NilError(t, cmd.Wait())
+	assert.Equal(t, len(mock.CapturedSignals()), 1)
+	assert.Equal(t, mock.CapturedSignals()[0], syscall.SIGTERM)
+
+	// Commands that have exited are no longer tracked.
+	assert.NilError(t, cmdexec.ShutdownAll(context.Background()))
+	assert.Equal(t, len(mock.CapturedSignals()), 1)
+}
@@ -0,0 +1,61 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import "time"
+
+// RunResult describes a single execution of a command, see
+// [Cmd.RunReport].
+type RunResult struct {
+	// Path is the resolved path of the command, see [Cmd.Path].
+	Path string
+
+	// Args are the arguments the command was run with, including the
+	// command name, see [Cmd.Argv].
+	Args []string
+
+	// Start and End are the times the command was started and finished
+	// at. Duration is the wall time between them.
+	Start    time.Time
+	End      time.Time
+	Duration time.Duration
+
+	// ExitCode is the exit code of the command, or -1 if it didn't exit
+	// (e.g., it failed to start or was killed by a signal).
+	ExitCode int
+
+	// Stdout and Stderr contain everything the command wrote to stdout
+	// and stderr respectively.
+	Stdout []byte
+	Stderr []byte
+}
+
+// newRunResult returns a [RunResult] for cmd, which was run from start
+// until now.
+func newRunResult(cmd Cmd, start time.Time, stdout, stderr []byte) *RunResult {
+	end := time.Now()
+	return &RunResult{
+		Path:     cmd.Path(),
+		Args:     cmd.Argv(),
+		Start:    start,
+		End:      end,
+		Duration: end.Sub(start),
+		ExitCode: cmd.ExitCode(),
+		Stdout:   stdout,
+		Stderr:   stderr,
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// RetryPolicy controls how [RunRetry] and [OutputRetry] retry commands
+// that fail.
+type RetryPolicy struct {
+	// Attempts is the maximum number of times the command is ran,
+	// including the first attempt. Values less than one are treated as
+	// one.
+	Attempts int
+
+	// Backoff is how long to wait before the first retry. The delay is
+	// doubled after each subsequent attempt.
+	Backoff time.Duration
+
+	// MaxBackoff is the maximum delay between attempts. If zero, the
+	// delay is not capped.
+	MaxBackoff time.Duration
+
+	// RetryableExitCodes, if set, limits retries to commands that exited
+	// with one of the provided exit codes. Otherwise, all errors are
+	// retried.
+	RetryableExitCodes []int
+}
+
+// RunRetry runs the command returned by newCmd, retrying it according
+// to the provided policy if it fails. A new command is created for
+// every attempt, as a [Cmd] can't be ran more than once. If ctx is done
+// while waiting to retry, the last error is returned joined with the
+// context's error.
+//
+// Usage:
+//
+//	err := cmdexec.RunRetry(ctx, cmdexec.RetryPolicy{Attempts: 3, Backoff: time.Second}, func() cmdexec.Cmd {
+//	    return cmdexec.CommandContext(ctx, "git", "fetch")
+//	})
+func RunRetry(ctx context.Context, p RetryPolicy, newCmd func() Cmd) error {
+	_, err := p.retry(ctx, func() ([]byte, error) {
+		return nil, newCmd().Run()
+	})
+	return err
+}
+
+// OutputRetry is like [RunRetry], but returns the output of the last
+// attempt, see [Cmd.Output].
+func OutputRetry(ctx context.Context, p RetryPolicy, newCmd func() Cmd) ([]byte, error) {
+	return p.retry(ctx, func() ([]byte, error) {
+		return newCmd().Output()
+	})
+}
+
+// retry calls fn until it succeeds or the policy is exhausted.
+func (p RetryPolicy) retry(ctx context.Context, fn func() ([]byte, error)) ([]byte, error) {
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		out, err := fn()
+		if err == nil || attempt >= p.Attempts || !p.retryable(err) {
+			return out, err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return out, errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+// retryable returns true if the provided error should be retried.
+func (p RetryPolicy) retryable(err error) bool {
+	if len(p.RetryableExitCodes) == 0 {
+		return true
+	}
+
+	var exitErr interface{ ExitCode() int }
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	return slices.Contains(p.RetryableExitCodes, exitErr.ExitCode())
+}
@@ -0,0 +1,58 @@
+package cmdexec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaredallard/cmdexec"
+	"gotest.tools/v3/assert"
+)
+
+// TestOutputRetry ensures that a command that fails is retried until it
+// succeeds.
+func TestOutputRetry(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name: "curl",
+		Responses: []cmdexec.MockResponse{
+			{ExitStatus: 7},
+			{ExitStatus: 7},
+		},
+		Stdout: []byte("ok"),
+	}))
+
+	newCmd := func() cmdexec.Cmd { return cmdexec.Command("curl") }
+
+	out, err := cmdexec.OutputRetry(context.Background(), cmdexec.RetryPolicy{
+		Attempts: 3,
+		Backoff:  time.Millisecond,
+	}, newCmd)
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "ok")
+}
+
+// TestRunRetryStops ensures that retries stop once the policy has been
+// exhausted or the error isn't retryable.
+func TestRunRetryStops(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name: "git",
+		Responses: []cmdexec.MockResponse{
+			{ExitStatus: 1},
+			{ExitStatus: 1},
+			{ExitStatus: 128},
+		},
+	}))
+
+	newCmd := func() cmdexec.Cmd { return cmdexec.Command("git") }
+
+	// Attempts are exhausted.
+	err := cmdexec.RunRetry(context.Background(), cmdexec.RetryPolicy{Attempts: 2}, newCmd)
+	assert.Error(t, err, "exit status 1")
+
+	// 128 isn't retryable.
+	err = cmdexec.RunRetry(context.Background(), cmdexec.RetryPolicy{
+		Attempts:           5,
+		RetryableExitCodes: []int{1},
+	}, newCmd)
+	assert.Error(t, err, "exit status 128")
+}
@@ -0,0 +1,45 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+// RlimitResource is a resource that can be limited through
+// [Cmd.SetRlimits].
+type RlimitResource int
+
+// Contains the resources that can be limited.
+const (
+	// RlimitAS limits the size of the virtual memory (address space) of
+	// the process, in bytes.
+	RlimitAS RlimitResource = iota
+
+	// RlimitCPU limits the CPU time of the process, in seconds.
+	RlimitCPU
+
+	// RlimitNOFILE limits the number of files the process can have
+	// open.
+	RlimitNOFILE
+)
+
+// Rlimit is a resource limit of a command, see [Cmd.SetRlimits]. Like
+// setrlimit(2), Cur is the soft limit and Max is the hard limit, where
+// math.MaxUint64 means unlimited. Limits of the address space are
+// rounded down to a multiple of 1024 bytes.
+type Rlimit struct {
+	Resource RlimitResource
+	Cur      uint64
+	Max      uint64
+}
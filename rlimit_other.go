@@ -0,0 +1,43 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+//go:build !unix
+
+package cmdexec
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// errRlimitsUnsupported is returned when setting resource limits, as
+// they are not supported on this platform.
+var errRlimitsUnsupported = errors.New("cmdexec: resource limits are not supported on this platform")
+
+// checkRlimits returns an error if any limits are provided, as resource
+// limits are not supported on this platform.
+func checkRlimits(limits []Rlimit) error {
+	if len(limits) > 0 {
+		return errRlimitsUnsupported
+	}
+	return nil
+}
+
+// wrapRlimits does nothing, as resource limits are not supported on
+// this platform and rejected by checkRlimits.
+func wrapRlimits(_ *exec.Cmd, _ []Rlimit) (restore func()) {
+	return func() {}
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+//go:build unix
+
+package cmdexec
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// rlimitFlags maps a [RlimitResource] to the flag of the ulimit shell
+// builtin setting it, and the unit of that flag in bytes (or 1).
+var rlimitFlags = map[RlimitResource]struct {
+	flag string
+	unit uint64
+}{
+	RlimitAS:     {"-v", 1024},
+	RlimitCPU:    {"-t", 1},
+	RlimitNOFILE: {"-n", 1},
+}
+
+// rlimitExitStatus is the exit code of a command whose resource limits
+// couldn't be set, in which case it isn't run.
+const rlimitExitStatus = 126
+
+// checkRlimits returns an error if limits can't be set by wrapRlimits,
+// so that it can be reported before the process is started.
+func checkRlimits(limits []Rlimit) error {
+	for _, limit := range limits {
+		if _, ok := rlimitFlags[limit.Resource]; !ok {
+			return fmt.Errorf("cmdexec: unknown rlimit resource %d", limit.Resource)
+		}
+	}
+	return nil
+}
+
+// wrapRlimits changes cmd to run through a shell that sets the provided
+// resource limits before executing the command, so that they apply
+// from its very start. The returned function restores the path and
+// arguments of cmd, which should be called once it has been started.
+// The limits must have been checked with checkRlimits.
+func wrapRlimits(cmd *exec.Cmd, limits []Rlimit) (restore func()) {
+	if len(limits) == 0 {
+		return func() {}
+	}
+
+	// The soft limit is lowered first, as the hard limit can't be set
+	// below it, then set again in case it could only be raised once the
+	// hard limit was.
+	script := make([]string, 0, len(limits)+1)
+	for _, limit := range limits {
+		f := rlimitFlags[limit.Resource]
+		soft, hard := ulimitValue(limit.Cur, f.unit), ulimitValue(limit.Max, f.unit)
+		script = append(script, fmt.Sprintf("ulimit -S %[1]s %[2]s 2>/dev/null; ulimit -H %[1]s %[3]s && ulimit -S %[1]s %[2]s || exit %[4]d",
+			f.flag, soft, hard, rlimitExitStatus,
+		))
+	}
+	script = append(script, `exec "$@"`)
+
+	path, args := cmd.Path, cmd.Args
+	cmd.Path = "/bin/sh"
+	if runtime.GOOS == "android" {
+		cmd.Path = "/system/bin/sh"
+	}
+	cmd.Args = append([]string{"sh", "-c", strings.Join(script, "; "), "sh", path}, args[1:]...)
+	return func() { cmd.Path, cmd.Args = path, args }
+}
+
+// ulimitValue formats the limit v, in bytes if unit isn't 1, for the
+// ulimit shell builtin.
+func ulimitValue(v, unit uint64) string {
+	if v == ^uint64(0) {
+		return "unlimited"
+	}
+	return strconv.FormatUint(v/unit, 10)
+}
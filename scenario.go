@@ -0,0 +1,250 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Scenario is a sequence of commands sharing state, allowing workflows
+// spanning multiple commands to be mocked declaratively, e.g.:
+//
+//	s := mock.Scenario()
+//	s.Step(&cmdexec.MockCommand{Name: "git", Args: []string{"init"}}).Unless("repo").Sets("repo")
+//	s.Step(&cmdexec.MockCommand{Name: "git", Args: []string{"commit"}}).Requires("repo")
+//	s.Step(&cmdexec.MockCommand{Name: "touch", Args: []string{"a"}}).Sets("a")
+//	s.Step(&cmdexec.MockCommand{Name: "ls", Stdout: []byte("a\n")}).Requires("a")
+//	s.Step(&cmdexec.MockCommand{Name: "ls"})
+//
+// The state is a set of names, which is changed by steps that exited
+// successfully. When a command is created, the first step matching it
+// (like [MockExecutor.AddCommand] would) whose requirements are met by
+// the current state is used. If steps matched the command but none of
+// them is allowed in the current state, the command fails to start with
+// [ErrScenario].
+//
+// Steps take precedence over commands added with AnyArgs, patterns and
+// matchers, but not over commands registered with exact arguments.
+type Scenario struct {
+	mu    sync.Mutex
+	e     *MockExecutor
+	state map[string]bool
+	steps []*ScenarioStep
+}
+
+// ScenarioStep is a command of a [Scenario], see [Scenario.Step].
+type ScenarioStep struct {
+	s   *Scenario
+	cmd *MockCommand
+
+	// matchArgs matches the arguments of commands, or is nil if they
+	// must be equal to the Args of cmd.
+	matchArgs func(args []string) bool
+
+	requires, unless, sets, clears []string
+}
+
+// Scenario creates a new [Scenario] for commands run through the
+// executor, with an empty state.
+func (e *MockExecutor) Scenario() *Scenario {
+	s := &Scenario{e: e, state: make(map[string]bool)}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.scenarios = append(e.scenarios, s)
+	return s
+}
+
+// Step adds cmd as a step of the scenario. Steps are matched in the
+// order they were added. cmd must not be added to the executor
+// otherwise.
+func (s *Scenario) Step(cmd *MockCommand) *ScenarioStep {
+	step := &ScenarioStep{s: s, cmd: cmd, matchArgs: cmd.argsMatcher()}
+
+	s.e.mu.Lock()
+	cmd.executor = s.e
+	s.e.mu.Unlock()
+
+	cmd.statsMu.Lock()
+	cmd.onExit = step.exited
+	cmd.statsMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.steps = append(s.steps, step)
+	return step
+}
+
+// Set adds states to the state of the scenario, e.g., to start from an
+// existing repository. It returns the scenario to allow chaining.
+func (s *Scenario) Set(states ...string) *Scenario {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, state := range states {
+		s.state[state] = true
+	}
+	return s
+}
+
+// Has reports whether state is part of the current state of the
+// scenario.
+func (s *Scenario) Has(state string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state[state]
+}
+
+// Requires makes the step only match if all of states are set. It
+// returns the step to allow chaining.
+func (step *ScenarioStep) Requires(states ...string) *ScenarioStep {
+	step.s.mu.Lock()
+	defer step.s.mu.Unlock()
+
+	step.requires = append(step.requires, states...)
+	return step
+}
+
+// Unless makes the step only match if none of states are set. It
+// returns the step to allow chaining.
+func (step *ScenarioStep) Unless(states ...string) *ScenarioStep {
+	step.s.mu.Lock()
+	defer step.s.mu.Unlock()
+
+	step.unless = append(step.unless, states...)
+	return step
+}
+
+// Sets adds states to the state of the scenario once the command of the
+// step exited successfully. It returns the step to allow chaining.
+func (step *ScenarioStep) Sets(states ...string) *ScenarioStep {
+	step.s.mu.Lock()
+	defer step.s.mu.Unlock()
+
+	step.sets = append(step.sets, states...)
+	return step
+}
+
+// Clears removes states from the state of the scenario once the command
+// of the step exited successfully. It returns the step to allow
+// chaining.
+func (step *ScenarioStep) Clears(states ...string) *ScenarioStep {
+	step.s.mu.Lock()
+	defer step.s.mu.Unlock()
+
+	step.clears = append(step.clears, states...)
+	return step
+}
+
+// Command returns the command of the step, e.g., to set expectations
+// like [MockCommand.Times].
+func (step *ScenarioStep) Command() *MockCommand {
+	return step.cmd
+}
+
+// matches reports whether a command with the provided name and
+// arguments matches the command of the step, regardless of the state.
+func (step *ScenarioStep) matches(name string, args []string) bool {
+	if !step.s.e.sameName(name, step.cmd.Name) {
+		return false
+	}
+	if step.matchArgs != nil {
+		return step.matchArgs(args)
+	}
+	return slices.Equal(args, step.cmd.Args)
+}
+
+// allowed reports whether the step is allowed in the current state of
+// the scenario. This must be called with s.mu held.
+func (step *ScenarioStep) allowed() bool {
+	for _, state := range step.requires {
+		if !step.s.state[state] {
+			return false
+		}
+	}
+	for _, state := range step.unless {
+		if step.s.state[state] {
+			return false
+		}
+	}
+	return true
+}
+
+// exited updates the state of the scenario once the command of the step
+// has exited with err.
+func (step *ScenarioStep) exited(err error) {
+	if err != nil {
+		return
+	}
+
+	step.s.mu.Lock()
+	defer step.s.mu.Unlock()
+
+	for _, state := range step.sets {
+		step.s.state[state] = true
+	}
+	for _, state := range step.clears {
+		delete(step.s.state, state)
+	}
+}
+
+// lookup returns the command of the first step that matches the
+// provided name and arguments and is allowed in the current state. If
+// steps matched but none is allowed, a command failing with
+// [ErrScenario] is returned. If no step matched, nil is returned.
+func (s *Scenario) lookup(name string, args []string) *MockCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matched := false
+	for _, step := range s.steps {
+		if !step.matches(name, args) {
+			continue
+		}
+		if step.allowed() {
+			return step.cmd
+		}
+		matched = true
+	}
+	if !matched {
+		return nil
+	}
+
+	states := slices.Sorted(maps.Keys(s.state))
+	return &MockCommand{Name: name, Args: args, executor: s.e, startErr: fmt.Errorf("%w: '%s' in state [%s]",
+		ErrScenario, strings.Join(append([]string{name}, args...), " "), strings.Join(states, " "),
+	)}
+}
+
+// commands returns the commands of all steps of the scenario.
+func (s *Scenario) commands() []*MockCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cmds := make([]*MockCommand, 0, len(s.steps))
+	for _, step := range s.steps {
+		cmds = append(cmds, step.cmd)
+	}
+	return cmds
+}
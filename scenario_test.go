@@ -0,0 +1,63 @@
+package cmdexec_test
+
+import (
+	"testing"
+
+	"github.com/jaredallard/cmdexec"
+	"gotest.tools/v3/assert"
+)
+
+// TestScenario ensures that steps of a scenario are only allowed in the
+// state set by previous steps, and that later output depends on it.
+func TestScenario(t *testing.T) {
+	mock := cmdexec.NewMockExecutor()
+	cmdexec.UseMockExecutor(t, mock)
+
+	s := mock.Scenario()
+	s.Step(&cmdexec.MockCommand{Name: "git", Args: []string{"init"}}).Unless("repo").Sets("repo")
+	s.Step(&cmdexec.MockCommand{Name: "git", Args: []string{"commit"}}).Requires("repo")
+	s.Step(&cmdexec.MockCommand{Name: "touch", Args: []string{"a"}}).Sets("a")
+	s.Step(&cmdexec.MockCommand{Name: "rm", Args: []string{"a"}}).Requires("a").Clears("a")
+	s.Step(&cmdexec.MockCommand{Name: "ls", Stdout: []byte("a\n")}).Requires("a")
+	s.Step(&cmdexec.MockCommand{Name: "ls"})
+
+	err := cmdexec.Command("git", "commit").Run()
+	assert.ErrorIs(t, err, cmdexec.ErrScenario)
+	assert.Error(t, err, "cmdexec: command not allowed by scenario: 'git commit' in state []")
+
+	assert.NilError(t, cmdexec.Command("git", "init").Run())
+	assert.NilError(t, cmdexec.Command("git", "commit").Run())
+	assert.ErrorIs(t, cmdexec.Command("git", "init").Run(), cmdexec.ErrScenario)
+
+	ls := func() string {
+		out, err := cmdexec.Command("ls").Output()
+		assert.NilError(t, err)
+		return string(out)
+	}
+	assert.Equal(t, ls(), "")
+	assert.NilError(t, cmdexec.Command("touch", "a").Run())
+	assert.Equal(t, ls(), "a\n")
+	assert.NilError(t, cmdexec.Command("rm", "a").Run())
+	assert.Equal(t, ls(), "")
+	assert.Equal(t, s.Has("repo"), true)
+}
+
+// TestScenarioFailedStep ensures that a step that exited with an error
+// doesn't change the state of the scenario.
+func TestScenarioFailedStep(t *testing.T) {
+	mock := cmdexec.NewMockExecutor()
+	cmdexec.UseMockExecutor(t, mock)
+
+	s := mock.Scenario().Set("repo")
+	s.Step(&cmdexec.MockCommand{
+		Name:      "git",
+		Args:      []string{"push"},
+		Responses: []cmdexec.MockResponse{{ExitStatus: 1}},
+	}).Requires("repo").Sets("pushed")
+
+	assert.Error(t, cmdexec.Command("git", "push").Run(), "exit status 1")
+	assert.Equal(t, s.Has("pushed"), false)
+
+	assert.NilError(t, cmdexec.Command("git", "push").Run())
+	assert.Equal(t, s.Has("pushed"), true)
+}
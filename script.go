@@ -0,0 +1,80 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ScriptStep is a step of the interactive conversation of a mocked
+// command, see [MockCommand.Script].
+type ScriptStep struct {
+	// Expect, if set, is the line the command reads from stdin before
+	// responding, without the trailing newline.
+	Expect string
+
+	// Respond is written to stdout once Expect has been read, e.g., the
+	// next prompt.
+	Respond []byte
+}
+
+// runScript runs the Script of the command, writing responses to
+// stdout. The responses are prepended to the stdout of the command's
+// response, so that they are returned by Output.
+func (c *MockCommand) runScript(stdout []io.Writer) error {
+	var stdin *bufio.Reader
+	switch {
+	case c.stdin != nil:
+		stdin = bufio.NewReader(c.stdin)
+	case c.stdinBytes != nil:
+		stdin = bufio.NewReader(bytes.NewReader(c.stdinBytes))
+	}
+
+	var out []byte
+	defer func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.resp.Stdout = append(out, c.resp.Stdout...)
+	}()
+
+	for i, step := range c.Script {
+		if step.Expect != "" {
+			if stdin == nil {
+				return fmt.Errorf("expected stdin line %q but no stdin was provided (was SetStdin() called?)", step.Expect)
+			}
+
+			line, err := stdin.ReadString('\n')
+			if err != nil && (err != io.EOF || line == "") {
+				return fmt.Errorf("expected stdin line %q for script step %d but got: %w", step.Expect, i, err)
+			}
+			if line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"); line != step.Expect {
+				return fmt.Errorf("expected stdin line %q for script step %d but got %q", step.Expect, i, line)
+			}
+		}
+
+		out = append(out, step.Respond...)
+		for _, w := range stdout {
+			w.Write(step.Respond) //nolint:errcheck // Why: Mirrors a process ignoring EPIPE.
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+// SignalResponse is how a mocked command reacts to a signal, see
+// [MockCommand.OnSignal].
+type SignalResponse struct {
+	// Delay is how long the command takes to exit once it has received
+	// the signal, e.g., to simulate a graceful shutdown.
+	Delay time.Duration
+
+	// ExitStatus is the exit code the command exits with. If zero, the
+	// command is terminated by the signal instead, like a process that
+	// doesn't handle it, and has no exit code.
+	ExitStatus int
+}
+
+// signalExit is the cause of a running command being interrupted
+// because it reacted to a signal.
+type signalExit struct {
+	sig  os.Signal
+	resp SignalResponse
+}
+
+// Error implements the error interface.
+func (e *signalExit) Error() string {
+	return "signal: " + e.sig.String()
+}
+
+// react interrupts the running invocation of the command once the
+// delay of its reaction to sig has elapsed, if it has one. Like SIGKILL,
+// [os.Kill] can't be handled and always terminates the command
+// immediately. This must be called with c.mu held.
+func (c *MockCommand) react(sig os.Signal) {
+	resp, ok := c.OnSignal[sig]
+	if sig == os.Kill {
+		resp, ok = SignalResponse{}, true
+	}
+	if !ok || c.interrupt == nil {
+		return
+	}
+
+	interrupt := c.interrupt
+	time.AfterFunc(resp.Delay, func() {
+		interrupt(&signalExit{sig: sig, resp: resp})
+	})
+}
+
+// signaled returns the response of the command if ctx was done because
+// the command reacted to a signal.
+func (c *MockCommand) signaled(ctx context.Context) (MockResponse, bool) {
+	var sig *signalExit
+	if !errors.As(context.Cause(ctx), &sig) {
+		return MockResponse{}, false
+	}
+
+	if sig.resp.ExitStatus != 0 {
+		return MockResponse{ExitStatus: sig.resp.ExitStatus}, true
+	}
+	return MockResponse{Err: &ExitError{Command: c.String(), Code: -1, Err: sig}}, true
+}
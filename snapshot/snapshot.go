@@ -0,0 +1,61 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+// Package snapshot provides snapshot testing of the commands executed
+// through a [cmdexec.MockExecutor], so that changes to which commands
+// are run are caught and reviewed deliberately:
+//
+//	mock := cmdexec.NewMockExecutor(&cmdexec.MockCommand{Name: "git", AnyArgs: true})
+//	cmdexec.UseMockExecutor(t, mock)
+//	snapshot.Commands(t, mock, "deploy.golden")
+//
+//	// Your test code here.
+//
+// Golden files are kept in the testdata directory of the package under
+// test and are created (or updated) by running its tests with the
+// -update flag, see [golden].
+package snapshot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jaredallard/cmdexec"
+	"gotest.tools/v3/golden"
+)
+
+// Commands compares the command lines of every command executed through
+// mock during the test against the golden file filename, one per line
+// in the order they were started, once the test has finished. Commands
+// run concurrently should be avoided, as their order isn't stable.
+func Commands(t testing.TB, mock *cmdexec.MockExecutor, filename string) {
+	t.Helper()
+
+	t.Cleanup(func() {
+		t.Helper()
+		golden.Assert(t, format(mock.Calls()), filename)
+	})
+}
+
+// format returns the command lines of calls, one per line.
+func format(calls []cmdexec.Invocation) string {
+	var b strings.Builder
+	for _, inv := range calls {
+		b.WriteString(inv.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
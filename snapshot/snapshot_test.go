@@ -0,0 +1,24 @@
+package snapshot_test
+
+import (
+	"testing"
+
+	"github.com/jaredallard/cmdexec"
+	"github.com/jaredallard/cmdexec/snapshot"
+	"gotest.tools/v3/assert"
+)
+
+// TestCommands ensures that the executed commands are compared against
+// the golden file, quoting arguments where needed.
+func TestCommands(t *testing.T) {
+	mock := cmdexec.NewMockExecutor(
+		&cmdexec.MockCommand{Name: "git", AnyArgs: true},
+		&cmdexec.MockCommand{Name: "kubectl", AnyArgs: true},
+	)
+	cmdexec.UseMockExecutor(t, mock)
+	snapshot.Commands(t, mock, "commands.golden")
+
+	assert.NilError(t, cmdexec.Command("git", "commit", "-m", "Add a feature").Run())
+	assert.NilError(t, cmdexec.Command("git", "push", "origin", "main").Run())
+	assert.NilError(t, cmdexec.Command("kubectl", "apply", "-f", "deploy.yaml").Run())
+}
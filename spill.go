@@ -0,0 +1,138 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+// OutputSpill runs cmd and returns its stdout, like [Cmd.Output], but
+// without buffering all of it in memory. Once the output exceeds
+// threshold bytes, it is written to a temporary file in dir instead
+// (or [os.TempDir] if dir is empty). This allows capturing the output
+// of commands that can produce a lot of it, e.g., database dumps.
+//
+// The returned reader is positioned at the start of the output and
+// must be closed, which removes the temporary file, if any.
+//
+// Usage:
+//
+//	out, err := cmdexec.OutputSpill(cmdexec.Command("pg_dump", "mydb"), 64<<20, "")
+//	if err != nil {
+//	    return err
+//	}
+//	defer out.Close()
+func OutputSpill(cmd Cmd, threshold int64, dir string) (io.ReadSeekCloser, error) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	buf := &spillBuffer{threshold: threshold, dir: dir}
+	if _, err := io.Copy(buf, stdout); err != nil {
+		cmd.Kill() //nolint:errcheck // Why: Best effort.
+		cmd.Wait() //nolint:errcheck // Why: Reading failed, which takes precedence.
+		buf.Close()
+		return nil, err
+	}
+
+	if err := cmd.Wait(); err != nil {
+		buf.Close()
+		return nil, err
+	}
+
+	return buf.reader()
+}
+
+// spillBuffer is an [io.Writer] that buffers in memory until threshold
+// bytes have been written, after which it writes to a temporary file
+// instead.
+type spillBuffer struct {
+	threshold int64
+	dir       string
+
+	buf bytes.Buffer
+	f   *os.File
+}
+
+// Write implements [io.Writer].
+func (b *spillBuffer) Write(p []byte) (int, error) {
+	if b.f == nil && int64(b.buf.Len()+len(p)) > b.threshold {
+		f, err := os.CreateTemp(b.dir, "cmdexec-output-*")
+		if err != nil {
+			return 0, err
+		}
+		b.f = f
+
+		if _, err := b.buf.WriteTo(f); err != nil {
+			return 0, err
+		}
+	}
+
+	if b.f != nil {
+		return b.f.Write(p)
+	}
+	return b.buf.Write(p)
+}
+
+// reader returns a reader over everything written to b, positioned at
+// the start.
+func (b *spillBuffer) reader() (io.ReadSeekCloser, error) {
+	if b.f == nil {
+		return nopSeekCloser{bytes.NewReader(b.buf.Bytes())}, nil
+	}
+
+	if _, err := b.f.Seek(0, io.SeekStart); err != nil {
+		b.Close()
+		return nil, err
+	}
+	return &tempFile{b.f}, nil
+}
+
+// Close removes the temporary file, if one was created.
+func (b *spillBuffer) Close() {
+	if b.f != nil {
+		(&tempFile{b.f}).Close() //nolint:errcheck // Why: Best effort.
+	}
+}
+
+// nopSeekCloser is an [io.ReadSeeker] with a no-op Close method.
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+// Close implements [io.Closer].
+func (nopSeekCloser) Close() error {
+	return nil
+}
+
+// tempFile is a temporary file that is removed when closed.
+type tempFile struct {
+	*os.File
+}
+
+// Close closes and removes the file.
+func (f *tempFile) Close() error {
+	return errors.Join(f.File.Close(), os.Remove(f.File.Name()))
+}
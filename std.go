@@ -17,10 +17,18 @@
 package cmdexec
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 // stdExecutorCmd is a simple wrapper around [exec.Cmd] to implement the
@@ -29,12 +37,485 @@ import (
 // All functions on this struct are not thread-safe.
 type stdExecutorCmd struct {
 	*exec.Cmd
+
+	// ctx is the context the command was created with, used by Clone.
+	ctx context.Context
+
+	// stdinBytes contains the stdin set by SetStdinString or
+	// SetStdinBytes, used by Clone to provide a fresh reader.
+	stdinBytes []byte
+
+	// credential is the user and group ID set through SetCredential.
+	credential *[2]uint32
+
+	// nice is the niceness set through SetNice.
+	nice *int
+
+	// rlimits are the resource limits set through SetRlimits.
+	rlimits []Rlimit
+
+	// newProcessGroup denotes if the command should be started in a new
+	// process group, which is tracked by group.
+	newProcessGroup bool
+	group           processGroup
+
+	// timeout is the timeout set through SetTimeout. timer is started
+	// once the command has been started if a timeout is set, setting
+	// timedOut once elapsed.
+	timeout  time.Duration
+	timer    *time.Timer
+	timedOut atomic.Bool
+
+	// ptySize is the size of the PTY to attach the command to, if set
+	// through UsePTY.
+	ptySize *[2]uint16
+
+	// pty is the controlling side of the PTY the command is attached to
+	// once started.
+	pty *os.File
+
+	// ptyCopyDone is closed once all output from the PTY has been copied
+	// to the stdout of the command, if one was set.
+	ptyCopyDone chan struct{}
+
+	// onStdoutLine and onStderrLine are the functions set through
+	// OnStdoutLine and OnStderrLine. Once started, output is passed to
+	// them through stdoutLines and stderrLines.
+	onStdoutLine func(string)
+	onStderrLine func(string)
+	stdoutLines  *lineWriter
+	stderrLines  *lineWriter
+
+	// stdoutFile and stderrFile are the files set through SetStdoutFile
+	// and SetStderrFile. Once started, the opened files are stored in
+	// openStdout and openStderr.
+	stdoutFile *outputFile
+	stderrFile *outputFile
+	openStdout *os.File
+	openStderr *os.File
+
+	// maxOutputBytes is the limit set through SetMaxOutputBytes.
+	maxOutputBytes int64
+
+	// teeStdout and teeStderr are the writers set through TeeStdout and
+	// TeeStderr.
+	teeStdout io.Writer
+	teeStderr io.Writer
+
+	// cancelSignal is the signal set through SetCancelSignal, if it
+	// wasn't replaced by SetCancel since. It is re-applied by Clone, as
+	// the cancel function refers to the command it was set on.
+	cancelSignal os.Signal
+
+	// waitOnce ensures the underlying [exec.Cmd] is only waited for
+	// once, by a goroutine started by Done. waitDone is closed once it
+	// has finished, with the result stored in waitErr.
+	waitOnce sync.Once
+	waitDone chan struct{}
+	waitErr  error
+
+	// captureStdout and captureStderr capture the output of the command
+	// for RunReport.
+	captureStdout *bytes.Buffer
+	captureStderr *bytes.Buffer
+
+	// stdoutPiped and stderrPiped denote if StdoutPipe or StderrPipe
+	// were called, in which case the line functions aren't used.
+	stdoutPiped bool
+	stderrPiped bool
 }
 
 // stdExecutor creates a new [Cmd] using [exec.CommandContext] as the
 // underlying executor.
 func stdExecutor(ctx context.Context, name string, arg ...string) Cmd {
-	return &stdExecutorCmd{exec.CommandContext(ctx, name, arg...)}
+	return &stdExecutorCmd{Cmd: exec.CommandContext(ctx, name, arg...), ctx: ctx}
+}
+
+// Clone implements [Cmd.Clone].
+func (c *stdExecutorCmd) Clone() Cmd {
+	clone := &stdExecutorCmd{
+		Cmd:             exec.CommandContext(c.ctx, c.Cmd.Args[0], c.Cmd.Args[1:]...),
+		ctx:             c.ctx,
+		newProcessGroup: c.newProcessGroup,
+		timeout:         c.timeout,
+		ptySize:         c.ptySize,
+		credential:      c.credential,
+		nice:            c.nice,
+		rlimits:         c.rlimits,
+		onStdoutLine:    c.onStdoutLine,
+		onStderrLine:    c.onStderrLine,
+		stdoutFile:      c.stdoutFile,
+		stderrFile:      c.stderrFile,
+		maxOutputBytes:  c.maxOutputBytes,
+		teeStdout:       c.teeStdout,
+		teeStderr:       c.teeStderr,
+	}
+	clone.Cmd.Env = slices.Clone(c.Cmd.Env)
+	clone.Cmd.Dir = c.Cmd.Dir
+	clone.Cmd.Stdout = c.Cmd.Stdout
+	clone.Cmd.Stderr = c.Cmd.Stderr
+	clone.Cmd.Stdin = c.Cmd.Stdin
+	if c.stdinBytes != nil {
+		clone.SetStdinBytes(c.stdinBytes)
+	}
+	clone.Cmd.ExtraFiles = slices.Clone(c.Cmd.ExtraFiles)
+	if c.Cmd.SysProcAttr != nil {
+		attr := *c.Cmd.SysProcAttr
+		clone.Cmd.SysProcAttr = &attr
+	}
+	if c.cancelSignal != nil {
+		clone.SetCancelSignal(c.cancelSignal, c.Cmd.WaitDelay)
+	}
+	clone.Cmd.WaitDelay = c.Cmd.WaitDelay
+	return clone
+}
+
+// Output implements [Cmd.Output].
+func (c *stdExecutorCmd) Output() ([]byte, error) {
+	if c.Cmd.Stdout != nil || c.stdoutFile != nil {
+		return nil, errors.New("exec: Stdout already set")
+	}
+
+	stdout := &limitedBuffer{max: c.maxOutputBytes}
+	c.Cmd.Stdout = stdout
+
+	// Like [exec.Cmd.Output], capture stderr into the returned
+	// [ExitError] if the caller isn't consuming it.
+	var stderr *bytes.Buffer
+	if c.Cmd.Stderr == nil && c.stderrFile == nil && c.ptySize == nil {
+		stderr = new(bytes.Buffer)
+		c.Cmd.Stderr = stderr
+	}
+
+	err := c.Run()
+
+	var exitErr *ExitError
+	if stderr != nil && errors.As(err, &exitErr) {
+		exitErr.Stderr = stderr.Bytes()
+
+		var execErr *exec.ExitError
+		if errors.As(err, &execErr) {
+			execErr.Stderr = exitErr.Stderr
+		}
+	}
+
+	return stdout.Bytes(), withTruncated(err, stdout.truncated)
+}
+
+// CombinedOutput implements [Cmd.CombinedOutput].
+func (c *stdExecutorCmd) CombinedOutput() ([]byte, error) {
+	if c.Cmd.Stdout != nil || c.stdoutFile != nil {
+		return nil, errors.New("exec: Stdout already set")
+	}
+	if c.Cmd.Stderr != nil || c.stderrFile != nil {
+		return nil, errors.New("exec: Stderr already set")
+	}
+
+	b := &limitedBuffer{max: c.maxOutputBytes}
+	c.Cmd.Stdout = b
+	c.Cmd.Stderr = b
+
+	err := c.Run()
+	return b.Bytes(), withTruncated(err, b.truncated)
+}
+
+// RunReport implements [Cmd.RunReport].
+func (c *stdExecutorCmd) RunReport() (*RunResult, error) {
+	c.captureStdout, c.captureStderr = new(bytes.Buffer), new(bytes.Buffer)
+
+	start := time.Now()
+	err := c.Run()
+	return newRunResult(c, start, c.captureStdout.Bytes(), c.captureStderr.Bytes()), err
+}
+
+// Run implements [Cmd.Run].
+func (c *stdExecutorCmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// Start implements [Cmd.Start].
+func (c *stdExecutorCmd) Start() error {
+	if err := c.prepare(); err != nil {
+		closeOutputFiles(c.openStdout, c.openStderr)
+		return err
+	}
+
+	// Resource limits are set by a shell executing the command, which is
+	// only visible while starting it.
+	restore := wrapRlimits(c.Cmd, c.rlimits)
+	var err error
+	if c.ptySize != nil {
+		err = c.startPTY()
+	} else {
+		err = c.Cmd.Start()
+	}
+	restore()
+	if err != nil {
+		closeOutputFiles(c.openStdout, c.openStderr)
+		return err
+	}
+
+	if c.newProcessGroup {
+		c.group.started(c.Cmd.Process)
+	}
+
+	if err := c.started(); err != nil {
+		c.Cmd.Process.Kill() //nolint:errcheck // Why: Best effort.
+		c.Wait()             //nolint:errcheck // Why: Killed above.
+		return err
+	}
+
+	if c.timeout > 0 {
+		c.timer = time.AfterFunc(c.timeout, c.cancelAfterTimeout)
+	}
+
+	track(c)
+	return nil
+}
+
+// started applies the options set on the command that can only be
+// applied to a running process.
+func (c *stdExecutorCmd) started() error {
+	if c.nice != nil {
+		if err := setNice(c.Cmd.Process, *c.nice); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cancelAfterTimeout cancels the command once its timeout has elapsed.
+// Like [exec.CommandContext], the Cancel function is used if set,
+// killing the process after WaitDelay if it hasn't exited.
+func (c *stdExecutorCmd) cancelAfterTimeout() {
+	c.timedOut.Store(true)
+
+	if c.Cmd.Cancel == nil {
+		c.Cmd.Process.Kill() //nolint:errcheck // Why: Best effort.
+		return
+	}
+
+	c.Cmd.Cancel() //nolint:errcheck // Why: Best effort.
+	if c.Cmd.WaitDelay > 0 {
+		time.AfterFunc(c.Cmd.WaitDelay, func() {
+			c.Cmd.Process.Kill() //nolint:errcheck // Why: Best effort.
+		})
+	}
+}
+
+// prepare applies the options set on the command to the underlying
+// [exec.Cmd] before it is started.
+func (c *stdExecutorCmd) prepare() error {
+	if c.credential != nil {
+		if err := setCredential(c.sysProcAttr(), c.credential[0], c.credential[1]); err != nil {
+			return err
+		}
+	}
+
+	if err := checkRlimits(c.rlimits); err != nil {
+		return err
+	}
+
+	// PTYs start the process in a new session, which also places it in
+	// a new process group. Attempting to do both fails.
+	if c.newProcessGroup && c.ptySize == nil {
+		c.group.configure(c.sysProcAttr())
+	}
+
+	if c.stdoutFile != nil || c.stderrFile != nil {
+		var err error
+		c.openStdout, c.openStderr, err = openOutputFiles(c.stdoutFile, c.stderrFile)
+		if err != nil {
+			return err
+		}
+		if c.openStdout != nil {
+			c.Cmd.Stdout = c.openStdout
+		}
+		if c.openStderr != nil {
+			c.Cmd.Stderr = c.openStderr
+		}
+	}
+
+	// Writers that output is copied to, in addition to the configured
+	// stdout and stderr.
+	var stdoutExtra, stderrExtra []io.Writer
+	if c.teeStdout != nil {
+		stdoutExtra = append(stdoutExtra, c.teeStdout)
+	}
+	if c.teeStderr != nil {
+		stderrExtra = append(stderrExtra, c.teeStderr)
+	}
+	if c.captureStdout != nil {
+		stdoutExtra = append(stdoutExtra, c.captureStdout)
+	}
+	if c.captureStderr != nil {
+		stderrExtra = append(stderrExtra, c.captureStderr)
+	}
+	if c.onStdoutLine != nil {
+		c.stdoutLines = &lineWriter{fn: c.onStdoutLine}
+		stdoutExtra = append(stdoutExtra, c.stdoutLines)
+	}
+	if c.onStderrLine != nil {
+		c.stderrLines = &lineWriter{fn: c.onStderrLine}
+		stderrExtra = append(stderrExtra, c.stderrLines)
+	}
+
+	if len(stdoutExtra) == 0 && len(stderrExtra) == 0 {
+		return nil
+	}
+
+	// Once wrapped, a shared writer (e.g., from CombinedOutput) would
+	// no longer be recognized as such by [exec.Cmd], which would then
+	// write to it concurrently.
+	stdout, stderr := c.Cmd.Stdout, c.Cmd.Stderr
+	if stdout != nil && interfaceEqual(stdout, stderr) {
+		w := &lockedWriter{w: stdout}
+		stdout, stderr = w, w
+	}
+
+	if len(stdoutExtra) > 0 && !c.stdoutPiped {
+		c.Cmd.Stdout = multiWriter(stdout, stdoutExtra...)
+	}
+	if len(stderrExtra) > 0 && !c.stderrPiped {
+		c.Cmd.Stderr = multiWriter(stderr, stderrExtra...)
+	}
+	return nil
+}
+
+// sysProcAttr returns the [syscall.SysProcAttr] of the underlying
+// [exec.Cmd], creating it if not already set.
+func (c *stdExecutorCmd) sysProcAttr() *syscall.SysProcAttr {
+	if c.Cmd.SysProcAttr == nil {
+		c.Cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	return c.Cmd.SysProcAttr
+}
+
+// startPTY starts the command attached to a new PTY. The standard
+// streams of the command are copied to and from the PTY.
+func (c *stdExecutorCmd) startPTY() error {
+	// The PTY replaces the standard streams of the process, so we copy
+	// them ourselves instead. Both stdout and stderr are written to the
+	// terminal, so we can only provide one stream.
+	stdout, stdin := c.Cmd.Stdout, c.Cmd.Stdin
+	if stdout == nil {
+		stdout = c.Cmd.Stderr
+	}
+	c.Cmd.Stdout, c.Cmd.Stderr, c.Cmd.Stdin = nil, nil, nil
+
+	f, err := startPTY(c.Cmd, c.ptySize[0], c.ptySize[1])
+	if err != nil {
+		return err
+	}
+	c.pty = f
+
+	if stdin != nil {
+		go io.Copy(f, stdin) //nolint:errcheck // Why: Best effort, like exec.Cmd.
+	}
+
+	if stdout != nil {
+		c.ptyCopyDone = make(chan struct{})
+		go func() {
+			defer close(c.ptyCopyDone)
+
+			// Reading from the PTY fails once the process has exited and
+			// closed its side of the terminal.
+			io.Copy(stdout, f) //nolint:errcheck // Why: See above.
+		}()
+	}
+
+	return nil
+}
+
+// Done implements [Cmd.Done].
+func (c *stdExecutorCmd) Done() <-chan struct{} {
+	if c.Cmd.Process == nil {
+		return nil
+	}
+
+	c.waitOnce.Do(func() {
+		c.waitDone = make(chan struct{})
+		go func() {
+			defer close(c.waitDone)
+			c.waitErr = c.wait()
+		}()
+	})
+	return c.waitDone
+}
+
+// Wait implements [Cmd.Wait]. Non-zero exit codes are returned as an
+// [ExitError].
+func (c *stdExecutorCmd) Wait() error {
+	done := c.Done()
+	if done == nil {
+		return c.Cmd.Wait()
+	}
+
+	<-done
+	return c.waitErr
+}
+
+// wait waits for the underlying [exec.Cmd] to exit, cleaning up after
+// it.
+func (c *stdExecutorCmd) wait() error {
+	err := c.Cmd.Wait()
+	untrack(c)
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.group.release()
+	closeOutputFiles(c.openStdout, c.openStderr)
+	if c.pty != nil {
+		if c.ptyCopyDone != nil {
+			<-c.ptyCopyDone
+		}
+		c.pty.Close() //nolint:errcheck // Why: Best effort.
+	}
+	if c.stdoutLines != nil {
+		c.stdoutLines.Flush()
+	}
+	if c.stderrLines != nil {
+		c.stderrLines.Flush()
+	}
+
+	if c.timedOut.Load() {
+		return newTimeoutError(c.timeout)
+	}
+
+	// Make it possible to tell that the command failed because it was
+	// canceled, even if it exited on its own due to the cancellation
+	// signal.
+	err = wrapExitError(c.Cmd.String(), err)
+	if ctxErr := c.ctx.Err(); err != nil && ctxErr != nil && !errors.Is(err, ctxErr) {
+		err = fmt.Errorf("%w: %w", ctxErr, err)
+	}
+	return err
+}
+
+// UsePTY implements [Cmd.UsePTY].
+func (c *stdExecutorCmd) UsePTY(rows, cols uint16) {
+	c.ptySize = &[2]uint16{rows, cols}
+}
+
+// PTY implements [Cmd.PTY].
+func (c *stdExecutorCmd) PTY() io.ReadWriter {
+	if c.pty == nil {
+		// Avoid returning a non-nil interface holding a nil pointer.
+		return nil
+	}
+	return c.pty
+}
+
+// ResizePTY implements [Cmd.ResizePTY].
+func (c *stdExecutorCmd) ResizePTY(rows, cols uint16) error {
+	if c.pty == nil {
+		return errNoPTY
+	}
+	return resizePTY(c.pty, rows, cols)
 }
 
 // String implements [Cmd.String].
@@ -42,16 +523,170 @@ func (c *stdExecutorCmd) String() string {
 	return c.Cmd.String()
 }
 
+// Path implements [Cmd.Path].
+func (c *stdExecutorCmd) Path() string {
+	return c.Cmd.Path
+}
+
+// Argv implements [Cmd.Argv].
+func (c *stdExecutorCmd) Argv() []string {
+	return append([]string(nil), c.Cmd.Args...)
+}
+
+// ExitCode implements [Cmd.ExitCode].
+func (c *stdExecutorCmd) ExitCode() int {
+	// ProcessState is nil until the process has exited, which
+	// [os.ProcessState.ExitCode] handles by returning -1.
+	return c.Cmd.ProcessState.ExitCode()
+}
+
+// PID implements [Cmd.PID].
+func (c *stdExecutorCmd) PID() int {
+	if c.Cmd.Process == nil {
+		return -1
+	}
+	return c.Cmd.Process.Pid
+}
+
+// Usage implements [Cmd.Usage].
+func (c *stdExecutorCmd) Usage() *Usage {
+	return newUsage(c.Cmd.ProcessState)
+}
+
+// ProcessState implements [Cmd.ProcessState].
+func (c *stdExecutorCmd) ProcessState() *ProcessState {
+	return newProcessState(c.Cmd.ProcessState)
+}
+
+// Signal implements [Cmd.Signal].
+func (c *stdExecutorCmd) Signal(sig os.Signal) error {
+	if c.Cmd.Process == nil {
+		return errors.New("exec: not started")
+	}
+	return c.Cmd.Process.Signal(sig)
+}
+
+// Kill implements [Cmd.Kill].
+func (c *stdExecutorCmd) Kill() error {
+	if c.Cmd.Process == nil {
+		return errors.New("exec: not started")
+	}
+	return c.Cmd.Process.Kill()
+}
+
 // SetEnviron implements [Cmd.SetEnviron].
 func (c *stdExecutorCmd) SetEnviron(env []string) {
 	c.Cmd.Env = env
 }
 
+// AppendEnv implements [Cmd.AppendEnv].
+func (c *stdExecutorCmd) AppendEnv(kv ...string) {
+	env := c.Cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	c.Cmd.Env = mergeEnv(env, kv...)
+}
+
+// SetEnv implements [Cmd.SetEnv].
+func (c *stdExecutorCmd) SetEnv(key, value string) {
+	c.AppendEnv(key + "=" + value)
+}
+
+// ScrubEnv implements [Cmd.ScrubEnv].
+func (c *stdExecutorCmd) ScrubEnv(patterns ...string) {
+	env := c.Cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	c.Cmd.Env = scrubEnv(env, patterns...)
+}
+
 // SetDir implements [Cmd.SetDir].
 func (c *stdExecutorCmd) SetDir(dir string) {
 	c.Cmd.Dir = dir
 }
 
+// SetSysProcAttr implements [Cmd.SetSysProcAttr].
+func (c *stdExecutorCmd) SetSysProcAttr(attr *syscall.SysProcAttr) {
+	c.Cmd.SysProcAttr = attr
+}
+
+// SetCredential implements [Cmd.SetCredential].
+func (c *stdExecutorCmd) SetCredential(uid, gid uint32) {
+	c.credential = &[2]uint32{uid, gid}
+}
+
+// SetNice implements [Cmd.SetNice].
+func (c *stdExecutorCmd) SetNice(level int) {
+	c.nice = &level
+}
+
+// SetRlimits implements [Cmd.SetRlimits].
+func (c *stdExecutorCmd) SetRlimits(limits ...Rlimit) {
+	c.rlimits = limits
+}
+
+// SetNewProcessGroup implements [Cmd.SetNewProcessGroup].
+func (c *stdExecutorCmd) SetNewProcessGroup(enabled bool) {
+	c.newProcessGroup = enabled
+}
+
+// KillGroup implements [Cmd.KillGroup].
+func (c *stdExecutorCmd) KillGroup() error {
+	if c.Cmd.Process == nil {
+		return errors.New("exec: not started")
+	}
+	if !c.newProcessGroup && c.ptySize == nil {
+		return errNoProcessGroup
+	}
+	return c.group.kill(c.Cmd.Process)
+}
+
+// KillTree implements [Cmd.KillTree].
+func (c *stdExecutorCmd) KillTree() error {
+	if c.Cmd.Process == nil {
+		return errors.New("exec: not started")
+	}
+
+	err := killTree(c.Cmd.Process)
+	if c.newProcessGroup || c.ptySize != nil {
+		// Also catches descendants that were re-parented.
+		c.group.kill(c.Cmd.Process) //nolint:errcheck // Why: Best effort, already killed above.
+	}
+	return err
+}
+
+// SetExtraFiles implements [Cmd.SetExtraFiles].
+func (c *stdExecutorCmd) SetExtraFiles(files []*os.File) {
+	c.Cmd.ExtraFiles = files
+}
+
+// SetCancel implements [Cmd.SetCancel].
+func (c *stdExecutorCmd) SetCancel(fn func() error) {
+	c.Cmd.Cancel = fn
+	c.cancelSignal = nil
+}
+
+// SetCancelSignal implements [Cmd.SetCancelSignal].
+func (c *stdExecutorCmd) SetCancelSignal(sig os.Signal, killAfter time.Duration) {
+	c.Cmd.Cancel = func() error {
+		return signalOrKill(c, sig)
+	}
+	c.Cmd.WaitDelay = killAfter
+	c.cancelSignal = sig
+}
+
+// SetTimeout implements [Cmd.SetTimeout].
+func (c *stdExecutorCmd) SetTimeout(d time.Duration) {
+	c.timeout = d
+}
+
+// SetWaitDelay implements [Cmd.SetWaitDelay].
+func (c *stdExecutorCmd) SetWaitDelay(d time.Duration) {
+	c.Cmd.WaitDelay = d
+}
+
 // SetStdout implements [Cmd.SetStdout].
 func (c *stdExecutorCmd) SetStdout(w io.Writer) {
 	c.Cmd.Stdout = w
@@ -65,6 +700,71 @@ func (c *stdExecutorCmd) SetStderr(w io.Writer) {
 // SetStdin implements [Cmd.SetStdin].
 func (c *stdExecutorCmd) SetStdin(r io.Reader) {
 	c.Cmd.Stdin = r
+	c.stdinBytes = nil
+}
+
+// SetStdinString implements [Cmd.SetStdinString].
+func (c *stdExecutorCmd) SetStdinString(s string) {
+	c.SetStdinBytes([]byte(s))
+}
+
+// SetStdinBytes implements [Cmd.SetStdinBytes].
+func (c *stdExecutorCmd) SetStdinBytes(b []byte) {
+	c.Cmd.Stdin = bytes.NewReader(b)
+	c.stdinBytes = b
+}
+
+// StdoutPipe implements [Cmd.StdoutPipe].
+func (c *stdExecutorCmd) StdoutPipe() (io.ReadCloser, error) {
+	r, err := c.Cmd.StdoutPipe()
+	if err == nil {
+		c.stdoutPiped = true
+	}
+	return r, err
+}
+
+// StderrPipe implements [Cmd.StderrPipe].
+func (c *stdExecutorCmd) StderrPipe() (io.ReadCloser, error) {
+	r, err := c.Cmd.StderrPipe()
+	if err == nil {
+		c.stderrPiped = true
+	}
+	return r, err
+}
+
+// OnStdoutLine implements [Cmd.OnStdoutLine].
+func (c *stdExecutorCmd) OnStdoutLine(fn func(string)) {
+	c.onStdoutLine = fn
+}
+
+// OnStderrLine implements [Cmd.OnStderrLine].
+func (c *stdExecutorCmd) OnStderrLine(fn func(string)) {
+	c.onStderrLine = fn
+}
+
+// SetStdoutFile implements [Cmd.SetStdoutFile].
+func (c *stdExecutorCmd) SetStdoutFile(path string, mode WriteMode) {
+	c.stdoutFile = &outputFile{path: path, mode: mode}
+}
+
+// SetStderrFile implements [Cmd.SetStderrFile].
+func (c *stdExecutorCmd) SetStderrFile(path string, mode WriteMode) {
+	c.stderrFile = &outputFile{path: path, mode: mode}
+}
+
+// SetMaxOutputBytes implements [Cmd.SetMaxOutputBytes].
+func (c *stdExecutorCmd) SetMaxOutputBytes(n int64) {
+	c.maxOutputBytes = n
+}
+
+// TeeStdout implements [Cmd.TeeStdout].
+func (c *stdExecutorCmd) TeeStdout(w io.Writer) {
+	c.teeStdout = w
+}
+
+// TeeStderr implements [Cmd.TeeStderr].
+func (c *stdExecutorCmd) TeeStderr(w io.Writer) {
+	c.teeStderr = w
 }
 
 // UseOSStreams implements [Cmd.UseOSStreams].
@@ -1,10 +1,20 @@
-//go:build !windows
+//go:build unix
 
 package cmdexec_test
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
 	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/jaredallard/cmdexec"
 	"gotest.tools/v3/assert"
@@ -35,3 +45,428 @@ func Test_stdExecutorSetEnviron(t *testing.T) {
 	assert.NilError(t, err)
 	assert.Equal(t, string(out), "1\n")
 }
+
+func Test_stdExecutorStartWait(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := cmdexec.Command("echo", "hello")
+	cmd.SetStdout(&buf)
+
+	assert.NilError(t, cmd.Start())
+	assert.NilError(t, cmd.Wait())
+	assert.Equal(t, buf.String(), "hello\n")
+}
+
+func Test_stdExecutorStdoutPipe(t *testing.T) {
+	cmd := cmdexec.Command("echo", "hello")
+	stdout, err := cmd.StdoutPipe()
+	assert.NilError(t, err)
+
+	assert.NilError(t, cmd.Start())
+	out, err := io.ReadAll(stdout)
+	assert.NilError(t, err)
+	assert.NilError(t, cmd.Wait())
+	assert.Equal(t, string(out), "hello\n")
+}
+
+func Test_stdExecutorExitCode(t *testing.T) {
+	cmd := cmdexec.Command("sh", "-c", "exit 2")
+	assert.Equal(t, cmd.ExitCode(), -1)
+	assert.Error(t, cmd.Run(), "exit status 2")
+	assert.Equal(t, cmd.ExitCode(), 2)
+}
+
+func Test_stdExecutorProcessState(t *testing.T) {
+	cmd := cmdexec.Command("true")
+	assert.Equal(t, cmd.PID(), -1)
+	assert.Assert(t, cmd.ProcessState() == nil)
+
+	assert.NilError(t, cmd.Run())
+	assert.Assert(t, cmd.PID() > 0)
+
+	state := cmd.ProcessState()
+	assert.Equal(t, state.Pid, cmd.PID())
+	assert.Equal(t, state.ExitCode, 0)
+	assert.Equal(t, state.Success, true)
+}
+
+func Test_stdExecutorKill(t *testing.T) {
+	cmd := cmdexec.Command("sleep", "10")
+	assert.Error(t, cmd.Kill(), "exec: not started")
+
+	assert.NilError(t, cmd.Start())
+	assert.NilError(t, cmd.Signal(syscall.SIGTERM))
+	assert.Error(t, cmd.Wait(), "signal: terminated")
+	assert.ErrorIs(t, cmd.Kill(), os.ErrProcessDone)
+}
+
+func Test_stdExecutorSetSysProcAttr(t *testing.T) {
+	cmd := cmdexec.Command("sh", "-c", "ps -o pid= -o sid= -p $$")
+	cmd.SetSysProcAttr(&syscall.SysProcAttr{Setsid: true})
+
+	out, err := cmd.Output()
+	assert.NilError(t, err)
+
+	// The process should be the leader of its own session.
+	fields := strings.Fields(string(out))
+	assert.Equal(t, len(fields), 2)
+	assert.Equal(t, fields[0], fields[1])
+}
+
+func Test_stdExecutorSetCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmd := cmdexec.CommandContext(ctx, "sleep", "10")
+	cmd.SetCancel(func() error { return cmd.Signal(syscall.SIGTERM) })
+	cmd.SetWaitDelay(5 * time.Second)
+
+	assert.NilError(t, cmd.Start())
+	cancel()
+	err := cmd.Wait()
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorContains(t, err, "signal: terminated")
+}
+
+func Test_stdExecutorEnviron(t *testing.T) {
+	cmd := cmdexec.Command("env")
+	cmd.SetEnviron([]string{"STENCIL_TEST_ENV=1"})
+	assert.DeepEqual(t, cmd.Environ(), []string{"STENCIL_TEST_ENV=1"})
+}
+
+func Test_stdExecutorPathAndArgv(t *testing.T) {
+	execPath, err := exec.LookPath("echo")
+	assert.NilError(t, err)
+
+	cmd := cmdexec.Command("echo", "hello")
+	assert.Equal(t, cmd.Path(), execPath)
+	assert.DeepEqual(t, cmd.Argv(), []string{"echo", "hello"})
+}
+
+func Test_stdExecutorSetExtraFiles(t *testing.T) {
+	r, w, err := os.Pipe()
+	assert.NilError(t, err)
+	defer r.Close()
+
+	cmd := cmdexec.Command("sh", "-c", "echo hello >&3")
+	cmd.SetExtraFiles([]*os.File{w})
+	assert.NilError(t, cmd.Run())
+	assert.NilError(t, w.Close())
+
+	out, err := io.ReadAll(r)
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "hello\n")
+}
+
+func Test_stdExecutorUsePTY(t *testing.T) {
+	cmd := cmdexec.Command("sh", "-c", "test -t 1 && stty size")
+	cmd.UsePTY(24, 80)
+
+	out, err := cmd.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "24 80\r\n")
+}
+
+func Test_stdExecutorResizePTY(t *testing.T) {
+	cmd := cmdexec.Command("sh", "-c", "read line; stty size")
+	assert.Error(t, cmd.ResizePTY(30, 100), "cmdexec: command is not attached to a PTY")
+
+	var out bytes.Buffer
+	cmd.SetStdout(&out)
+	cmd.UsePTY(24, 80)
+	assert.NilError(t, cmd.Start())
+
+	assert.NilError(t, cmd.ResizePTY(30, 100))
+	_, err := cmd.PTY().Write([]byte("\n"))
+	assert.NilError(t, err)
+	assert.NilError(t, cmd.Wait())
+	assert.Assert(t, strings.HasSuffix(out.String(), "30 100\r\n"), out.String())
+}
+
+func Test_stdExecutorKillGroup(t *testing.T) {
+	cmd := cmdexec.Command("sh", "-c", "sleep 10 & echo started; wait")
+	stdout, err := cmd.StdoutPipe()
+	assert.NilError(t, err)
+
+	assert.NilError(t, cmd.Start())
+	assert.Error(t, cmd.KillGroup(), "cmdexec: command was not started in a new process group")
+	assert.NilError(t, cmd.Kill())
+	assert.Error(t, cmd.Wait(), "signal: killed")
+
+	cmd = cmdexec.Command("sh", "-c", "sleep 10 & echo started; wait")
+	cmd.SetNewProcessGroup(true)
+	stdout, err = cmd.StdoutPipe()
+	assert.NilError(t, err)
+
+	assert.NilError(t, cmd.Start())
+	started := time.Now()
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	assert.NilError(t, err)
+	assert.Equal(t, line, "started\n")
+	assert.NilError(t, cmd.KillGroup())
+
+	// If the background sleep wasn't killed, stdout wouldn't be closed
+	// until it exited.
+	_, err = io.ReadAll(stdout)
+	assert.NilError(t, err)
+	assert.Error(t, cmd.Wait(), "signal: killed")
+	assert.Assert(t, time.Since(started) < 5*time.Second)
+}
+
+func Test_stdExecutorOutputLines(t *testing.T) {
+	var lines []string
+	for line, err := range cmdexec.OutputLines(cmdexec.Command("printf", "a\\nb\\n")) {
+		assert.NilError(t, err)
+		lines = append(lines, line)
+	}
+	assert.DeepEqual(t, lines, []string{"a", "b"})
+}
+
+func Test_stdExecutorExitError(t *testing.T) {
+	cmd := cmdexec.Command("sh", "-c", "echo oops >&2; exit 3")
+	_, err := cmd.Output()
+	assert.Error(t, err, "exit status 3: oops")
+
+	var exitErr *cmdexec.ExitError
+	assert.Assert(t, errors.As(err, &exitErr))
+	assert.Equal(t, exitErr.Command, cmd.String())
+	assert.Equal(t, exitErr.ExitCode(), 3)
+	assert.Equal(t, string(exitErr.Stderr), "oops\n")
+
+	// The original error is still available.
+	var execErr *exec.ExitError
+	assert.Assert(t, errors.As(err, &execErr))
+	assert.Equal(t, string(execErr.Stderr), "oops\n")
+}
+
+func Test_stdExecutorSetTimeout(t *testing.T) {
+	cmd := cmdexec.Command("sleep", "10")
+	cmd.SetTimeout(10 * time.Millisecond)
+	assert.ErrorIs(t, cmd.Run(), context.DeadlineExceeded)
+
+	cmd = cmdexec.Command("true")
+	cmd.SetTimeout(time.Minute)
+	assert.NilError(t, cmd.Run())
+}
+
+func Test_stdExecutorClone(t *testing.T) {
+	cmd := cmdexec.Command("sh", "-c", "cat; echo $STENCIL_TEST_ENV; pwd")
+	cmd.SetEnv("STENCIL_TEST_ENV", "hello")
+	cmd.SetDir("/")
+	cmd.SetStdinString("stdin\n")
+
+	for range 2 {
+		clone := cmd.Clone()
+		out, err := clone.Output()
+		assert.NilError(t, err)
+		assert.Equal(t, string(out), "stdin\nhello\n/\n")
+	}
+}
+
+func Test_stdExecutorOnLine(t *testing.T) {
+	var stdout, stderr []string
+	cmd := cmdexec.Command("sh", "-c", "printf 'a\\nb\\n'; printf 'c\\nd' >&2")
+	cmd.OnStdoutLine(func(line string) { stdout = append(stdout, line) })
+	cmd.OnStderrLine(func(line string) { stderr = append(stderr, line) })
+
+	out, err := cmd.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "a\nb\n")
+	assert.DeepEqual(t, stdout, []string{"a", "b"})
+	assert.DeepEqual(t, stderr, []string{"c", "d"})
+}
+
+func Test_stdExecutorSetCredential(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("changing credentials requires root")
+	}
+
+	cmd := cmdexec.Command("id", "-u")
+	cmd.SetCredential(65534, 65534)
+	out, err := cmd.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "65534\n")
+}
+
+func Test_stdExecutorSetNice(t *testing.T) {
+	cmd := cmdexec.Command("sh", "-c", "sleep 0.1; ps -o nice= -p $$")
+	cmd.SetNice(10)
+	out, err := cmd.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, strings.TrimSpace(string(out)), "10")
+}
+
+func Test_stdExecutorSetRlimits(t *testing.T) {
+	cmd := cmdexec.Command("sh", "-c", "ulimit -Sn; ulimit -Hn; ulimit -St; ulimit -Ht")
+	cmd.SetRlimits(
+		cmdexec.Rlimit{Resource: cmdexec.RlimitNOFILE, Cur: 64, Max: 128},
+		cmdexec.Rlimit{Resource: cmdexec.RlimitCPU, Cur: 10, Max: 20},
+	)
+	argv := cmd.Argv()
+	out, err := cmd.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "64\n128\n10\n20\n")
+
+	// The shell setting the limits isn't visible.
+	assert.DeepEqual(t, cmd.Argv(), argv)
+}
+
+func Test_stdExecutorSetRlimitsFailure(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the maximum number of open files differs between platforms")
+	}
+
+	// Linux doesn't allow more open files than fs.nr_open, even to root.
+	cmd := cmdexec.Command("echo", "ran")
+	cmd.SetRlimits(cmdexec.Rlimit{Resource: cmdexec.RlimitNOFILE, Cur: 1 << 40, Max: 1 << 40})
+	out, err := cmd.Output()
+	assert.ErrorContains(t, err, "exit status 126")
+	assert.Equal(t, string(out), "")
+}
+
+func Test_stdExecutorSetRlimitsNotStarted(t *testing.T) {
+	cmd := cmdexec.Command("true")
+	cmd.SetRlimits(cmdexec.Rlimit{Resource: -1})
+	assert.Assert(t, cmd.Start() != nil)
+	assert.Equal(t, cmd.PID(), -1)
+}
+
+func Test_stdExecutorTeeStdout(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	cmd := cmdexec.Command("sh", "-c", "echo out; echo err >&2")
+	cmd.TeeStdout(&stdout)
+	cmd.TeeStderr(&stderr)
+
+	out, err := cmd.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "out\n")
+	assert.Equal(t, stdout.String(), "out\n")
+	assert.Equal(t, stderr.String(), "err\n")
+}
+
+func Test_stdExecutorRunReport(t *testing.T) {
+	cmd := cmdexec.Command("sh", "-c", "echo out; echo err >&2; exit 3")
+	res, err := cmd.RunReport()
+	assert.ErrorContains(t, err, "exit status 3")
+	assert.Equal(t, res.ExitCode, 3)
+	assert.Equal(t, string(res.Stdout), "out\n")
+	assert.Equal(t, string(res.Stderr), "err\n")
+	assert.Equal(t, res.Path, cmd.Path())
+	assert.Assert(t, res.Duration > 0)
+}
+
+func Test_stdExecutorDone(t *testing.T) {
+	cmd := cmdexec.Command("sh", "-c", "exit 2")
+	assert.Assert(t, cmd.Done() == nil)
+	assert.NilError(t, cmd.Start())
+
+	select {
+	case <-cmd.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("command did not finish")
+	}
+	assert.ErrorContains(t, cmd.Wait(), "exit status 2")
+	assert.Equal(t, cmd.ExitCode(), 2)
+}
+
+func Test_stdExecutorSetStdoutFile(t *testing.T) {
+	path := t.TempDir() + "/out.log"
+	assert.NilError(t, os.WriteFile(path, []byte("existing\n"), 0o644))
+
+	cmd := cmdexec.Command("sh", "-c", "echo out; echo err >&2")
+	cmd.SetStdoutFile(path, cmdexec.WriteAppend)
+	cmd.SetStderrFile(path, cmdexec.WriteAppend)
+	assert.NilError(t, cmd.Run())
+
+	b, err := os.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "existing\nout\nerr\n")
+
+	cmd = cmdexec.Command("echo", "truncated")
+	cmd.SetStdoutFile(path, cmdexec.WriteTruncate)
+	assert.NilError(t, cmd.Run())
+
+	b, err = os.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "truncated\n")
+}
+
+func Test_stdExecutorScrubEnv(t *testing.T) {
+	t.Setenv("STENCIL_TEST_SECRET", "hunter2")
+
+	cmd := cmdexec.Command("sh", "-c", "echo \"${STENCIL_TEST_SECRET:-scrubbed}\"")
+	cmd.ScrubEnv("STENCIL_TEST_*")
+	out, err := cmd.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "scrubbed\n")
+}
+
+func Test_stdExecutorSetCancelSignal(t *testing.T) {
+	t.Run("Original", func(t *testing.T) { testStdExecutorSetCancelSignal(t, false) })
+	t.Run("Clone", func(t *testing.T) { testStdExecutorSetCancelSignal(t, true) })
+}
+
+func testStdExecutorSetCancelSignal(t *testing.T, clone bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmd := cmdexec.CommandContext(ctx, "sh", "-c", "trap 'echo terminated; exit 1' TERM; echo started; sleep 10 & wait")
+	cmd.SetCancelSignal(syscall.SIGTERM, 5*time.Second)
+	if clone {
+		cmd = cmd.Clone()
+	}
+	stdout, err := cmd.StdoutPipe()
+	assert.NilError(t, err)
+	assert.NilError(t, cmd.Start())
+
+	r := bufio.NewReader(stdout)
+	line, err := r.ReadString('\n')
+	assert.NilError(t, err)
+	assert.Equal(t, line, "started\n")
+
+	cancel()
+	line, err = r.ReadString('\n')
+	assert.NilError(t, err)
+	assert.Equal(t, line, "terminated\n")
+
+	err = cmd.Wait()
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, cmd.ExitCode(), 1)
+}
+
+func Test_stdExecutorUsage(t *testing.T) {
+	cmd := cmdexec.Command("sh", "-c", "head -c 10000000 /dev/zero | tail -c 1 >/dev/null")
+	assert.Assert(t, cmd.Usage() == nil)
+	assert.NilError(t, cmd.Run())
+
+	usage := cmd.Usage()
+	assert.Assert(t, usage != nil)
+	assert.Assert(t, usage.MaxRSS > 0)
+}
+
+func Test_stdExecutorKillTree(t *testing.T) {
+	cmd := cmdexec.Command("sh", "-c", "sleep 10 & echo started; wait")
+	stdout, err := cmd.StdoutPipe()
+	assert.NilError(t, err)
+
+	assert.NilError(t, cmd.Start())
+	started := time.Now()
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	assert.NilError(t, err)
+	assert.Equal(t, line, "started\n")
+	assert.NilError(t, cmd.KillTree())
+
+	// If the background sleep wasn't killed, stdout wouldn't be closed
+	// until it exited.
+	_, err = io.ReadAll(stdout)
+	assert.NilError(t, err)
+	assert.Error(t, cmd.Wait(), "signal: killed")
+	assert.Assert(t, time.Since(started) < 5*time.Second)
+}
+
+func Test_stdExecutorSetMaxOutputBytes(t *testing.T) {
+	cmd := cmdexec.Command("sh", "-c", "yes | head -c 100000")
+	cmd.SetMaxOutputBytes(10)
+	out, err := cmd.Output()
+	assert.ErrorIs(t, err, cmdexec.ErrOutputTruncated)
+	assert.Equal(t, string(out), "y\ny\ny\ny\ny\n")
+}
@@ -0,0 +1,66 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Terminate gracefully stops a started command. SIGTERM is sent to the
+// command and, if it hasn't exited after the grace period (or ctx is
+// done first), it is killed. Terminate waits for the command to exit
+// and returns the error returned by [Cmd.Wait], so Wait should not be
+// called on the command again.
+//
+// On Windows, where sending SIGTERM is not supported, the command is
+// killed immediately.
+func Terminate(ctx context.Context, cmd Cmd, grace time.Duration) error {
+	if err := cmd.Signal(syscall.SIGTERM); err != nil {
+		// The command was already waited on, so there's nothing to do.
+		if errors.Is(err, os.ErrProcessDone) {
+			return nil
+		}
+
+		// Sending SIGTERM isn't supported on all platforms, so fall back
+		// to killing the command.
+		if err := cmd.Kill(); err != nil {
+			return err
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	if err := cmd.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	return <-done
+}
@@ -0,0 +1,73 @@
+package cmdexec_test
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jaredallard/cmdexec"
+	"gotest.tools/v3/assert"
+)
+
+// TestTerminateMock ensures that terminating a mocked command sends it
+// SIGTERM.
+func TestTerminateMock(t *testing.T) {
+	mock := &cmdexec.MockCommand{Name: "server"}
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(mock))
+
+	cmd := cmdexec.Command("server")
+	assert.NilError(t, cmd.Start())
+	assert.NilError(t, cmdexec.Terminate(context.Background(), cmd, time.Second))
+	assert.DeepEqual(t, mock.CapturedSignals(), []os.Signal{syscall.SIGTERM})
+
+	// Terminating an already finished command is a no-op.
+	assert.NilError(t, cmdexec.Terminate(context.Background(), cmd, time.Second))
+}
+
+// TestTerminateBlockingMock ensures that a mocked command that ignores
+// SIGTERM is killed once the grace period has elapsed.
+func TestTerminateBlockingMock(t *testing.T) {
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(&cmdexec.MockCommand{
+		Name:     "server",
+		Blocking: true,
+		OnSignal: map[os.Signal]cmdexec.SignalResponse{os.Kill: {Delay: time.Hour, ExitStatus: 0}},
+	}))
+
+	cmd := cmdexec.Command("server")
+	assert.NilError(t, cmd.Start())
+
+	start := time.Now()
+	assert.Error(t, cmdexec.Terminate(context.Background(), cmd, 50*time.Millisecond), "signal: killed")
+	assert.Assert(t, time.Since(start) < time.Second)
+	assert.Equal(t, cmd.ExitCode(), -1)
+}
+
+// TestTerminate ensures that a command is sent SIGTERM first and only
+// killed if it doesn't exit within the grace period.
+func TestTerminate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGTERM is not supported on Windows")
+	}
+
+	start := func(script string) cmdexec.Cmd {
+		cmd := cmdexec.Command("sh", "-c", script+"; echo ready; sleep 10 & wait")
+		stdout, err := cmd.StdoutPipe()
+		assert.NilError(t, err)
+		assert.NilError(t, cmd.Start())
+
+		// Wait for the trap to be installed.
+		_, err = bufio.NewReader(stdout).ReadString('\n')
+		assert.NilError(t, err)
+		return cmd
+	}
+
+	cmd := start(`trap "exit 3" TERM`)
+	assert.Error(t, cmdexec.Terminate(context.Background(), cmd, 5*time.Second), "exit status 3")
+
+	cmd = start(`trap "" TERM`)
+	assert.Error(t, cmdexec.Terminate(context.Background(), cmd, 100*time.Millisecond), "signal: killed")
+}
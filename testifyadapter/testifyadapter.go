@@ -0,0 +1,107 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+// Package testifyadapter provides a [cmdexec.MockExecutor] backed by
+// a [mock.Mock], allowing teams that use testify to mock commands with
+// On, Return, and AssertExpectations:
+//
+//	m := testifyadapter.New()
+//	m.OnCommand("git", "status").Return(testifyadapter.Result{Stdout: []byte("clean\n")}).Once()
+//	cmdexec.UseMockExecutor(t, m.MockExecutor())
+//
+//	// Your test code here.
+//
+//	m.AssertExpectations(t)
+package testifyadapter
+
+import (
+	"fmt"
+
+	"github.com/jaredallard/cmdexec"
+	"github.com/stretchr/testify/mock"
+)
+
+// method is the name of the method that is called on the [mock.Mock]
+// every time a command runs.
+const method = "Command"
+
+// Result is the result of a mocked command, which must be returned by
+// every call set up on an [Executor].
+type Result struct {
+	// Stdout and Stderr are the output of the command.
+	Stdout []byte
+	Stderr []byte
+
+	// ExitStatus is the exit code of the command. If non-zero, the
+	// command fails with a [cmdexec.ExitError] wrapping Err.
+	ExitStatus int
+
+	// Err is the error the command fails with, if any.
+	Err error
+}
+
+// Executor is a [mock.Mock] that is called every time a command
+// created through [Executor.MockExecutor] runs, with the name and the
+// arguments of the command as a []string. Use [Executor.OnCommand] to
+// set up a call, returning a [Result].
+type Executor struct {
+	mock.Mock
+}
+
+// New returns a new Executor.
+func New() *Executor {
+	return &Executor{}
+}
+
+// OnCommand sets up a call for the command with the provided name and
+// arguments, see [mock.Mock.On]. To match commands using
+// [mock.Anything] or [mock.MatchedBy] instead, call On with the method
+// "Command", the name, and the arguments.
+func (e *Executor) OnCommand(name string, args ...string) *mock.Call {
+	return e.On(method, name, append([]string{}, args...))
+}
+
+// MockExecutor returns a [cmdexec.MockExecutor] that uses e for every
+// command, which can be used with [cmdexec.UseMockExecutor] (or
+// [cmdexec.WithMockExecutor]). Commands that weren't set up fail with
+// an error describing the closest call that was.
+func (e *Executor) MockExecutor() *cmdexec.MockExecutor {
+	executor := cmdexec.NewMockExecutor()
+	executor.SetDefault(&cmdexec.MockCommand{Handler: e.run})
+	return executor
+}
+
+// run calls the mock for the command described by inv.
+func (e *Executor) run(inv cmdexec.Invocation) (stdout, stderr []byte, err error) {
+	// Calls that weren't set up panic, which would crash the test when
+	// not recovered as the command runs in its own goroutine.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("testifyadapter: %v", r)
+		}
+	}()
+
+	rets := e.MethodCalled(method, inv.Name, append([]string{}, inv.Args...))
+	res, ok := rets.Get(0).(Result)
+	if !ok {
+		return nil, nil, fmt.Errorf("testifyadapter: expected call to return a Result, got %T", rets.Get(0))
+	}
+
+	if res.ExitStatus != 0 {
+		return res.Stdout, res.Stderr, &cmdexec.ExitError{Code: res.ExitStatus, Err: res.Err}
+	}
+	return res.Stdout, res.Stderr, res.Err
+}
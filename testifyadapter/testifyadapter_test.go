@@ -0,0 +1,37 @@
+package testifyadapter_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jaredallard/cmdexec"
+	"github.com/jaredallard/cmdexec/testifyadapter"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/v3/assert"
+)
+
+// TestExecutor ensures that commands are mocked through the calls set
+// up on the testify mock.
+func TestExecutor(t *testing.T) {
+	m := testifyadapter.New()
+	m.OnCommand("git", "status").Return(testifyadapter.Result{Stdout: []byte("clean\n")}).Twice()
+	m.OnCommand("git", "push").Return(testifyadapter.Result{Stderr: []byte("rejected\n"), ExitStatus: 1})
+	m.On("Command", "git", mock.MatchedBy(func(args []string) bool {
+		return len(args) > 0 && args[0] == "checkout"
+	})).Return(testifyadapter.Result{})
+	cmdexec.UseMockExecutor(t, m.MockExecutor())
+
+	for range 2 {
+		out, err := cmdexec.Command("git", "status").Output()
+		assert.NilError(t, err)
+		assert.Equal(t, string(out), "clean\n")
+	}
+
+	assert.Error(t, cmdexec.Command("git", "push").Run(), "exit status 1: rejected")
+	assert.NilError(t, cmdexec.Command("git", "checkout", "main").Run())
+
+	err := cmdexec.Command("git", "fetch").Run()
+	assert.Assert(t, err != nil && strings.HasPrefix(err.Error(), "testifyadapter: "), err)
+
+	m.AssertExpectations(t)
+}
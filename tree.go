@@ -0,0 +1,64 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import "os"
+
+// killTree kills p and all of its descendants. Descendants are found
+// by walking the parent process IDs of all running processes, so
+// descendants that were re-parented (e.g., daemons whose parent exited)
+// are not found. If processes can't be listed on this platform, only p
+// is killed.
+func killTree(p *os.Process) error {
+	pids, _ := descendants(p.Pid) //nolint:errcheck // Why: Best effort, p is still killed.
+
+	// Kill p first so it can't spawn new children.
+	err := p.Kill()
+	for _, pid := range pids {
+		if proc, err := os.FindProcess(pid); err == nil {
+			proc.Kill() //nolint:errcheck // Why: Best effort, it may have exited already.
+		}
+	}
+	return err
+}
+
+// descendants returns the process IDs of all descendants of pid,
+// ordered from the closest to the furthest descendant.
+func descendants(pid int) ([]int, error) {
+	parents, err := processParents()
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[int][]int)
+	for child, parent := range parents {
+		children[parent] = append(children[parent], child)
+	}
+
+	var pids []int
+	seen := map[int]bool{pid: true}
+	for queue := []int{pid}; len(queue) > 0; queue = queue[1:] {
+		for _, child := range children[queue[0]] {
+			if !seen[child] {
+				seen[child] = true
+				pids = append(pids, child)
+				queue = append(queue, child)
+			}
+		}
+	}
+	return pids, nil
+}
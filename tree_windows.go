@@ -0,0 +1,53 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// processParents returns the parent process ID of every running
+// process, keyed by process ID, using a Toolhelp snapshot.
+func processParents() (map[int]int, error) {
+	snapshot, err := syscall.CreateToolhelp32Snapshot(syscall.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("CreateToolhelp32Snapshot", err)
+	}
+	defer syscall.CloseHandle(snapshot) //nolint:errcheck // Why: Best effort.
+
+	var entry syscall.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	if err := syscall.Process32First(snapshot, &entry); err != nil {
+		return nil, os.NewSyscallError("Process32First", err)
+	}
+
+	parents := make(map[int]int)
+	for {
+		parents[int(entry.ProcessID)] = int(entry.ParentProcessID)
+
+		err := syscall.Process32Next(snapshot, &entry)
+		if errors.Is(err, syscall.ERROR_NO_MORE_FILES) {
+			return parents, nil
+		}
+		if err != nil {
+			return nil, os.NewSyscallError("Process32Next", err)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// txtarStreams are the streams of a command in a txtar archive, in the
+// order they must appear in, see [ParseTxtar].
+var txtarStreams = []string{"env", "stdin", "stdout", "stderr", "exit"}
+
+// txtarNoNewline is the suffix of a stream in a txtar archive whose
+// data doesn't end with a newline, which is otherwise always added.
+const txtarNoNewline = " (no newline)"
+
+// LoadTxtar reads the mocked commands from the txtar archive at path,
+// see [ParseTxtar].
+func LoadTxtar(path string) ([]*MockCommand, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cmds, err := ParseTxtar(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cmds, nil
+}
+
+// ParseTxtar parses mocked commands from a txtar archive (see
+// golang.org/x/tools/txtar), allowing large outputs to be kept in
+// readable fixtures next to the tests using them. The name of each
+// file in the archive is the stream it contains followed by the command
+// line of the command, which is split like [CommandString] does:
+//
+//	Comments before the first file are ignored.
+//	-- stdout: kubectl get pods --
+//	NAME    READY   STATUS
+//	web-0   1/1     Running
+//	-- stderr: kubectl get pods --
+//	Warning: kubectl is out of date
+//	-- exit: kubectl delete pod web-0 --
+//	1
+//
+// A stream is one of "env", "stdin", "stdout", "stderr", or "exit". The
+// "env" stream contains the environment variables the command is
+// expected to be called with (see [MockCommand.ExpectedEnv]), one
+// "key=value" pair per line, the "stdin" stream the expected stdin (see
+// [MockCommand.Stdin]), and the "exit" stream the exit code of the
+// command. Like in txtar archives, a newline is added to data that
+// doesn't end with one, unless the stream is followed by
+// " (no newline)", e.g., "-- stdin (no newline): git apply --". The
+// streams of a single invocation of a command must appear in that
+// order, though any of them may be omitted. A stream that doesn't
+// follow that order for the same command starts its next invocation
+// (see [MockCommand.Responses]), e.g., a second "stdout" file. The last
+// invocation is repeated once all of them have been used. The returned
+// commands can be passed to [NewMockExecutor].
+func ParseTxtar(data []byte) ([]*MockCommand, error) {
+	var cmds []*MockCommand
+	type state struct {
+		cmd   *MockCommand
+		resps []MockResponse
+		last  int
+	}
+	states := make(map[string]*state)
+
+	for _, f := range parseTxtarFiles(data) {
+		stream, cmdline, ok := strings.Cut(f.name, ": ")
+		if s, found := strings.CutSuffix(stream, txtarNoNewline); found {
+			stream = s
+			f.data = bytes.TrimSuffix(f.data, []byte("\n"))
+		}
+		order := slices.Index(txtarStreams, stream)
+		if !ok || order == -1 {
+			return nil, fmt.Errorf("cmdexec: invalid txtar file name %q, "+
+				"expected \"<stream>: <command>\" with one of the streams %s", f.name, strings.Join(txtarStreams, ", "))
+		}
+
+		words, err := splitCommandLine(cmdline)
+		if err != nil {
+			return nil, fmt.Errorf("cmdexec: invalid txtar file name %q: %w", f.name, err)
+		}
+
+		key := strings.Join(words, "\x00")
+		s, ok := states[key]
+		if !ok {
+			s = &state{cmd: &MockCommand{Name: words[0], Args: words[1:]}}
+			states[key] = s
+			cmds = append(cmds, s.cmd)
+		}
+		if len(s.resps) == 0 || order <= s.last {
+			s.resps = append(s.resps, MockResponse{})
+		}
+		s.last = order
+
+		resp := &s.resps[len(s.resps)-1]
+		switch stream {
+		case "env":
+			resp.ExpectedEnv = strings.Split(strings.TrimSuffix(string(f.data), "\n"), "\n")
+		case "stdin":
+			resp.Stdin = f.data
+		case "stdout":
+			resp.Stdout = f.data
+		case "stderr":
+			resp.Stderr = f.data
+		case "exit":
+			resp.ExitStatus, err = strconv.Atoi(string(bytes.TrimSpace(f.data)))
+			if err != nil {
+				return nil, fmt.Errorf("cmdexec: invalid exit code in txtar file %q: %w", f.name, err)
+			}
+		}
+	}
+
+	for _, s := range states {
+		last := s.resps[len(s.resps)-1]
+		s.cmd.Stdin, s.cmd.ExpectedEnv = last.Stdin, last.ExpectedEnv
+		s.cmd.Stdout, s.cmd.Stderr, s.cmd.ExitStatus = last.Stdout, last.Stderr, last.ExitStatus
+		if len(s.resps) == 1 {
+			continue
+		}
+
+		// Prevent invocations without stdin or environment from using
+		// those of the command, i.e., the last invocation.
+		for i := range s.resps {
+			if s.resps[i].Stdin == nil {
+				s.resps[i].Stdin = []byte{}
+			}
+			if s.resps[i].ExpectedEnv == nil {
+				s.resps[i].ExpectedEnv = []string{}
+			}
+		}
+		s.cmd.Responses = s.resps
+	}
+
+	return cmds, nil
+}
+
+// txtarFile is a file in a txtar archive.
+type txtarFile struct {
+	name string
+	data []byte
+}
+
+// parseTxtarFiles returns the files in the txtar archive data. Like
+// golang.org/x/tools/txtar, a final newline is added to files that
+// don't end with one.
+func parseTxtarFiles(data []byte) []txtarFile {
+	var files []txtarFile
+	for len(data) > 0 {
+		var line []byte
+		line, data, _ = bytes.Cut(data, []byte("\n"))
+
+		if name, ok := txtarMarker(line); ok {
+			files = append(files, txtarFile{name: name, data: []byte{}})
+			continue
+		}
+
+		// Anything before the first file is a comment.
+		if len(files) > 0 {
+			f := &files[len(files)-1]
+			f.data = append(append(f.data, line...), '\n')
+		}
+	}
+	return files
+}
+
+// txtarMarker returns the file name if line is a txtar file marker,
+// i.e., "-- name --".
+func txtarMarker(line []byte) (string, bool) {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if !bytes.HasPrefix(line, []byte("-- ")) || !bytes.HasSuffix(line, []byte(" --")) || len(line) < 6 {
+		return "", false
+	}
+
+	name := strings.TrimSpace(string(line[3 : len(line)-3]))
+	return name, name != ""
+}
@@ -0,0 +1,40 @@
+package cmdexec_test
+
+import (
+	"testing"
+
+	"github.com/jaredallard/cmdexec"
+	"gotest.tools/v3/assert"
+)
+
+// TestLoadTxtar ensures that mocked commands can be loaded from a txtar
+// archive.
+func TestLoadTxtar(t *testing.T) {
+	cmds, err := cmdexec.LoadTxtar("testdata/kubectl.txtar")
+	assert.NilError(t, err)
+	cmdexec.UseMockExecutor(t, cmdexec.NewMockExecutor(cmds...))
+
+	out, err := cmdexec.Command("kubectl", "get", "pods").CombinedOutput()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "NAME    READY   STATUS    RESTARTS   AGE\n"+
+		"web-0   1/1     Running   0          3d\n"+
+		"Warning: kubectl is out of date\n")
+
+	out, err = cmdexec.Command("kubectl", "delete", "pod", "web-0").Output()
+	assert.ErrorContains(t, err, `pods "web-0" is forbidden`)
+	assert.Equal(t, string(out), "")
+
+	out, err = cmdexec.Command("kubectl", "delete", "pod", "web-0").Output()
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "pod \"web-0\" deleted\n")
+}
+
+// TestParseTxtarInvalid ensures that archives with invalid file names
+// are rejected.
+func TestParseTxtarInvalid(t *testing.T) {
+	_, err := cmdexec.ParseTxtar([]byte("-- stdio: cat --\nhello\n"))
+	assert.ErrorContains(t, err, `invalid txtar file name "stdio: cat"`)
+
+	_, err = cmdexec.ParseTxtar([]byte("-- exit: false --\nnope\n"))
+	assert.ErrorContains(t, err, `invalid exit code in txtar file "exit: false"`)
+}
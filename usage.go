@@ -0,0 +1,50 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+package cmdexec
+
+import (
+	"os"
+	"time"
+)
+
+// Usage contains resource usage statistics of an exited command, see
+// [Cmd.Usage].
+type Usage struct {
+	// UserTime and SystemTime are the user and system CPU time of the
+	// exited process and its children.
+	UserTime   time.Duration
+	SystemTime time.Duration
+
+	// MaxRSS is the maximum resident set size of the exited process, in
+	// bytes. It is zero on platforms that don't report it (e.g.,
+	// Windows).
+	MaxRSS int64
+}
+
+// newUsage creates a [Usage] from the provided [os.ProcessState]. If ps
+// is nil, nil is returned.
+func newUsage(ps *os.ProcessState) *Usage {
+	if ps == nil {
+		return nil
+	}
+
+	return &Usage{
+		UserTime:   ps.UserTime(),
+		SystemTime: ps.SystemTime(),
+		MaxRSS:     maxRSS(ps),
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright (C) 2024 Jared Allard <jaredallard@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by  the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public
+// License along with this program. If not, see
+// <https://www.gnu.org/licenses/>.
+
+//go:build unix
+
+package cmdexec
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// maxRSS returns the maximum resident set size of the exited process,
+// in bytes.
+func maxRSS(ps *os.ProcessState) int64 {
+	rusage, ok := ps.SysUsage().(*syscall.Rusage)
+	if !ok || rusage == nil {
+		return 0
+	}
+
+	// Most platforms report the maximum resident set size in kilobytes,
+	// except for Apple's.
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		return int64(rusage.Maxrss)
+	}
+	return int64(rusage.Maxrss) * 1024
+}